@@ -19,34 +19,115 @@ func main() {
 		panic(fmt.Sprintf("failed to load config: %v", err))
 	}
 
-	logger.Init(&logger.LoggerConfig{Enabled: cfg.LoggerEnabled})
+	level, err := logger.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		panic(fmt.Sprintf("failed to init logger: %v", err))
+	}
+	format, err := logger.ParseFormat(cfg.Log.Format)
+	if err != nil {
+		panic(fmt.Sprintf("failed to init logger: %v", err))
+	}
+	overflow, err := logger.ParseOverflow(cfg.Log.Overflow)
+	if err != nil {
+		panic(fmt.Sprintf("failed to init logger: %v", err))
+	}
+	var sinks []logger.Sink
+	if cfg.LoggerEnabled {
+		if sinks, err = openLogSinks(cfg.Log.Sinks); err != nil {
+			panic(fmt.Sprintf("failed to init logger: %v", err))
+		}
+	}
+	logger.Init(&logger.LoggerConfig{
+		Enabled:    cfg.LoggerEnabled,
+		Level:      level,
+		Format:     format,
+		BufferSize: cfg.Log.BufferSize,
+		Overflow:   overflow,
+		Sinks:      sinks,
+	})
 
-	rest := rest.NewRest(cfg)
-	logger.LogInfo("starting server...")
+	rest, err := rest.NewRest(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("failed to init server: %v", err))
+	}
+	logger.Info("starting server...")
 
 	shutdownCh := make(chan struct{})
 	go func() {
 		if err := rest.RunRest(); err != nil {
-			logger.LogError(fmt.Sprintf("failed to start server: %v", err))
+			logger.Error("failed to start server", "error", err)
 			close(shutdownCh)
 		}
 	}()
 
+	// SIGHUP reopens the log files, so logrotate can move them away.
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			if err := logger.Reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to reopen log files: %v\n", err)
+			} else {
+				logger.Info("log files reopened")
+			}
+		}
+	}()
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
 	select {
 	case sig := <-sigCh:
-		logger.LogInfo(fmt.Sprintf("received signal %s, shutting down server", sig))
+		logger.Info("shutting down server", "signal", sig.String())
 	case <-shutdownCh:
-		logger.LogInfo("server shutdown")
+		logger.Info("server shutdown")
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := rest.ShutdownRest(ctx); err != nil {
-		logger.LogError(fmt.Sprintf("graceful shutdown failed: %v", err))
+		logger.Error("graceful shutdown failed", "error", err)
 	} else {
-		logger.LogInfo("graceful shutdown succeed")
+		logger.Info("graceful shutdown succeed")
+	}
+	if err := logger.Close(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// openLogSinks opens the configured log destinations.
+func openLogSinks(cfgs []config.LogSinkConfig) ([]logger.Sink, error) {
+	var sinks []logger.Sink
+	for i, c := range cfgs {
+		format := c.Format
+		if format != "" {
+			var err error
+			if format, err = logger.ParseFormat(format); err != nil {
+				return nil, fmt.Errorf("log sink %d: %w", i, err)
+			}
+		}
+		sink := logger.Sink{Format: format}
+		switch c.Type {
+		case "stdout":
+			sink.Output = os.Stdout
+		case "", "stderr":
+			sink.Output = os.Stderr
+		case "file":
+			f, err := logger.OpenFile(logger.FileConfig{
+				Path:       c.Path,
+				MaxSize:    int64(c.MaxSizeMB) << 20,
+				MaxAge:     time.Duration(c.MaxAge),
+				MaxBackups: c.MaxBackups,
+				Compress:   c.Compress,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("log sink %d: %w", i, err)
+			}
+			sink.Output = f
+		default:
+			return nil, fmt.Errorf("log sink %d: type must be \"stdout\", \"stderr\" or \"file\", got %q", i, c.Type)
+		}
+		sinks = append(sinks, sink)
 	}
+	return sinks, nil
 }
@@ -0,0 +1,107 @@
+// Package apperr defines the errors the services and the repository report
+// to their callers. Each carries a kind, which decides the HTTP status, a
+// stable code for clients to match on, and a message that is safe to show.
+// The underlying cause is kept for logs only.
+package apperr
+
+import (
+	"errors"
+	"time"
+)
+
+// Kind classifies an error by what the client can do about it.
+type Kind string
+
+const (
+	Internal     Kind = "internal"
+	NotFound     Kind = "not_found"
+	Validation   Kind = "validation"
+	Conflict     Kind = "conflict"
+	Precondition Kind = "precondition"
+	RateLimited  Kind = "rate_limited"
+
+	// BadRequest is a request that cannot be read at all, such as malformed
+	// JSON or an invalid id.
+	BadRequest Kind = "bad_request"
+	// Unsupported is a request body in a media type the endpoint does not
+	// take.
+	Unsupported Kind = "unsupported_media_type"
+	// NotAcceptable is a request for a response type the endpoint cannot
+	// produce.
+	NotAcceptable Kind = "not_acceptable"
+	// TooLarge is a request body over the size limit.
+	TooLarge Kind = "too_large"
+)
+
+// FieldError describes one invalid value of the input. Pointer is a JSON
+// pointer (RFC 6901) to it, such as "/title".
+type FieldError struct {
+	Pointer string `json:"pointer"`
+	Detail  string `json:"detail"`
+}
+
+// Error is an application error.
+type Error struct {
+	Kind Kind
+	// Code is a stable identifier such as "task_not_found".
+	Code string
+	// Message is shown to clients, except for internal errors.
+	Message string
+	Fields  []FieldError
+	// RetryAfter is how long a rate-limited client should wait.
+	RetryAfter time.Duration
+	// Err is the cause, if any.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil && e.Err.Error() != e.Message {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is matches errors with the same code, so a sentinel such as a "not found"
+// still matches after it has been wrapped with more detail.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// New returns an error of the given kind.
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap returns an error of the given kind whose message is err's.
+func Wrap(err error, kind Kind, code string) *Error {
+	return &Error{Kind: kind, Code: code, Message: err.Error(), Err: err}
+}
+
+// fieldErrors is implemented by errors that know which fields they concern,
+// such as models.ValidationError.
+type fieldErrors interface {
+	FieldErrors() []FieldError
+}
+
+// Invalid marks err as a validation failure, keeping its field details.
+func Invalid(err error) *Error {
+	e := Wrap(err, Validation, "validation_failed")
+	var fe fieldErrors
+	if errors.As(err, &fe) {
+		e.Fields = fe.FieldErrors()
+	}
+	return e
+}
+
+// From returns the *Error in err's chain. Any other error is reported as
+// internal, with err kept as the cause.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Kind: Internal, Code: "internal", Message: "internal server error", Err: err}
+}
@@ -0,0 +1,44 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type fieldErr struct{}
+
+func (fieldErr) Error() string { return "title is required" }
+func (fieldErr) FieldErrors() []FieldError {
+	return []FieldError{{Pointer: "/title", Detail: "title is required"}}
+}
+
+func TestIsMatchesByCode(t *testing.T) {
+	notFound := New(NotFound, "task_not_found", "task not found")
+	wrapped := fmt.Errorf("update: %w", &Error{Kind: NotFound, Code: "task_not_found", Message: "task 7 not found"})
+
+	if !errors.Is(wrapped, notFound) {
+		t.Errorf("expected a copy with the same code to match")
+	}
+	if errors.Is(New(NotFound, "other", "task not found"), notFound) {
+		t.Errorf("expected a different code not to match")
+	}
+}
+
+func TestInvalidKeepsFields(t *testing.T) {
+	e := Invalid(fmt.Errorf("create: %w", fieldErr{}))
+	if e.Kind != Validation || len(e.Fields) != 1 || e.Fields[0].Pointer != "/title" {
+		t.Fatalf("expected a validation error on title, got %+v", e)
+	}
+}
+
+func TestFromDefaultsToInternal(t *testing.T) {
+	cause := errors.New("disk full")
+	e := From(cause)
+	if e.Kind != Internal || !errors.Is(e, cause) {
+		t.Fatalf("expected an internal error wrapping the cause, got %+v", e)
+	}
+	if e.Message == cause.Error() {
+		t.Errorf("expected the message not to repeat the cause")
+	}
+}
@@ -4,16 +4,141 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"task-manager/pkg/logger"
 )
 
 type Config struct {
-	AppPort       string `json:"app_port"`
-	LoggerEnabled bool   `json:"logger_enabled"`
+	AppPort       string      `json:"app_port"`
+	LoggerEnabled bool        `json:"logger_enabled"`
+	Store         StoreConfig `json:"store"`
+	// TaskTransitions maps each status to the statuses a task may move to.
+	// When empty the built-in table is used.
+	TaskTransitions map[string][]string `json:"task_transitions"`
+	// CursorSecret signs pagination cursors. When empty a random key is
+	// generated at startup and cursors expire on restart.
+	CursorSecret string `json:"cursor_secret"`
+	// APIVersions marks API versions, keyed "v1" or "v2", as deprecated or
+	// due for sunset.
+	APIVersions map[string]APIVersionConfig `json:"api_versions"`
+	// Validation tightens the rules for task fields.
+	Validation ValidationConfig `json:"validation"`
+	// MaxBodyBytes caps JSON request bodies; zero keeps the 1 MiB default.
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+	// ServerFields is "ignore" (the default) or "reject": what to do when a
+	// client sends a task's id, version or timestamps.
+	ServerFields string `json:"server_fields"`
+	// Log configures the logger enabled by LoggerEnabled.
+	Log LogConfig `json:"log"`
+	// AccessLog records every request through the logger.
+	AccessLog AccessLogConfig `json:"access_log"`
 	// feel free to add more fields
 }
 
+type StoreConfig struct {
+	// Driver names the storage engine; empty selects the in-memory store.
+	Driver string `json:"driver"`
+	// DataDir is where the write-ahead log lives; empty keeps tasks in memory only.
+	DataDir string `json:"data_dir"`
+	// WALSync is one of "always", "interval" or "never".
+	WALSync         string   `json:"wal_sync"`
+	WALSyncInterval Duration `json:"wal_sync_interval"`
+	// SnapshotInterval of zero disables periodic snapshots.
+	SnapshotInterval Duration `json:"snapshot_interval"`
+	SnapshotRetain   int      `json:"snapshot_retain"`
+}
+
+// ValidationConfig holds per-field limits; fields left out keep the
+// built-in limits.
+type ValidationConfig struct {
+	Title       TextLimitsConfig `json:"title"`
+	Description TextLimitsConfig `json:"description"`
+}
+
+// TextLimitsConfig bounds a text field. Lengths count characters; zero keeps
+// the built-in value. Pattern is a regular expression the whole value must
+// match.
+type TextLimitsConfig struct {
+	MinLength int    `json:"min_length"`
+	MaxLength int    `json:"max_length"`
+	Pattern   string `json:"pattern"`
+}
+
+// APIVersionConfig announces the retirement of an API version in response
+// headers. Times are RFC 3339.
+type APIVersionConfig struct {
+	Deprecation time.Time `json:"deprecation,omitzero"`
+	Sunset      time.Time `json:"sunset,omitzero"`
+	// Link points clients to migration notes.
+	Link string `json:"link"`
+}
+
+type LogConfig struct {
+	// Level is the least severe level written: "debug", "info" (the
+	// default), "warn", "error" or "fatal".
+	Level string `json:"level"`
+	// Format is "text" (the default) or "json", one object per line.
+	Format string `json:"format"`
+	// BufferSize is how many records may wait to be written; zero means 100.
+	BufferSize int `json:"buffer_size"`
+	// Overflow is what happens when the buffer is full: "block" (the
+	// default), "drop_newest" or "drop_oldest".
+	Overflow string `json:"overflow"`
+	// Sinks are where records are written; when empty they go to stderr.
+	Sinks []LogSinkConfig `json:"sinks"`
+}
+
+// LogSinkConfig is one log destination. The rotation settings apply to
+// "file" sinks; zero disables each of them.
+type LogSinkConfig struct {
+	// Type is "stdout", "stderr" or "file".
+	Type string `json:"type"`
+	// Format overrides the log format for this sink.
+	Format string `json:"format"`
+	Path   string `json:"path"`
+	// MaxSizeMB rotates the file once it would grow past this many MiB.
+	MaxSizeMB int `json:"max_size_mb"`
+	// MaxAge rotates the file once it has been written to for this long.
+	MaxAge     Duration `json:"max_age"`
+	MaxBackups int      `json:"max_backups"`
+	// Compress gzips rotated files.
+	Compress bool `json:"compress"`
+}
+
+type AccessLogConfig struct {
+	Enabled bool `json:"enabled"`
+	// Format is "common", "combined" (the default) or "json".
+	Format string `json:"format"`
+	// Exclude lists paths that are not logged; one ending in "*" covers
+	// every path it is a prefix of.
+	Exclude []string `json:"exclude"`
+}
+
+// Duration reads a time.Duration from a JSON string such as "500ms".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"1s\": %w", err)
+	}
+	if s == "" {
+		*d = 0
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
 func LoadConfig() (*Config, error) {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
@@ -21,14 +146,14 @@ func LoadConfig() (*Config, error) {
 	}
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		logger.LogError(fmt.Sprintf("failed to load config: %v", err))
+		logger.Error("failed to load config", "path", configPath, "error", err)
 		return nil, err
 	}
 
 	var config Config
 	err = json.Unmarshal(data, &config)
 	if err != nil {
-		logger.LogError(fmt.Sprintf("failed to unmarshal config: %v", err))
+		logger.Error("failed to unmarshal config", "path", configPath, "error", err)
 		return nil, err
 	}
 
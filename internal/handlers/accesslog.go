@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-manager/pkg/logger"
+)
+
+// AccessLogFormat is how an access log record is written.
+type AccessLogFormat string
+
+const (
+	// CommonLog writes the Common Log Format line as the message.
+	CommonLog AccessLogFormat = "common"
+	// CombinedLog adds the referer and user agent to CommonLog.
+	CombinedLog AccessLogFormat = "combined"
+	// JSONLog writes each value as a field of its own, so a JSON sink gets
+	// one object per request.
+	JSONLog AccessLogFormat = "json"
+)
+
+// ParseAccessLogFormat reads an access log format; empty means combined.
+func ParseAccessLogFormat(s string) (AccessLogFormat, error) {
+	switch AccessLogFormat(s) {
+	case "", CombinedLog:
+		return CombinedLog, nil
+	case CommonLog, JSONLog:
+		return AccessLogFormat(s), nil
+	default:
+		return "", fmt.Errorf("access log format must be %q, %q or %q, got %q", CommonLog, CombinedLog, JSONLog, s)
+	}
+}
+
+// clfTime is the timestamp layout of the Common Log Format.
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLog records every request it serves through pkg/logger. The CLF
+// formats carry the request ID and latency as fields next to the line.
+type AccessLog struct {
+	Format AccessLogFormat
+	// Exclude lists paths that are not logged, such as health checks. A
+	// path ending in "*" excludes every path it is a prefix of.
+	Exclude []string
+}
+
+// Record wraps next, logging each request once it has been served. It reads
+// the request ID from the response, so it belongs outside RequestID.
+func (a AccessLog) Record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.excluded(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		a.log(r, rec, start, time.Since(start))
+	})
+}
+
+func (a AccessLog) excluded(path string) bool {
+	for _, exclude := range a.Exclude {
+		if prefix, ok := strings.CutSuffix(exclude, "*"); ok && strings.HasPrefix(path, prefix) || path == exclude {
+			return true
+		}
+	}
+	return false
+}
+
+func (a AccessLog) log(r *http.Request, rec *responseRecorder, start time.Time, latency time.Duration) {
+	ctx := r.Context()
+	requestID := rec.Header().Get(RequestIDHeader)
+	latencyMS := float64(latency.Microseconds()) / 1000
+	if a.Format == JSONLog {
+		logger.InfoContext(ctx, "request",
+			"request_id", requestID,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"query", r.URL.RawQuery,
+			"status", rec.status(),
+			"bytes", rec.bytes,
+			"latency_ms", latencyMS,
+			"remote_addr", r.RemoteAddr,
+			"user_agent", r.UserAgent(),
+		)
+		return
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	size := "-"
+	if rec.bytes > 0 {
+		size = strconv.FormatInt(rec.bytes, 10)
+	}
+	line := fmt.Sprintf("%s - - [%s] %q %d %s", host, start.Format(clfTime), r.Method+" "+r.RequestURI+" "+r.Proto, rec.status(), size)
+	if a.Format == CombinedLog {
+		line += fmt.Sprintf(" %q %q", dash(r.Referer()), dash(r.UserAgent()))
+	}
+	logger.InfoContext(ctx, line, "request_id", requestID, "latency_ms", latencyMS)
+}
+
+// dash stands in for an empty CLF field.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// responseRecorder notes the status and size of a response on its way out.
+type responseRecorder struct {
+	http.ResponseWriter
+	code  int
+	bytes int64
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	// Informational responses come before the final one.
+	if rec.code == 0 && code >= http.StatusOK {
+		rec.code = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if rec.code == 0 {
+		rec.code = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(p)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, so
+// streaming responses can still flush.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// status is the code sent, or 200 when the handler wrote nothing.
+func (rec *responseRecorder) status() int {
+	if rec.code == 0 {
+		return http.StatusOK
+	}
+	return rec.code
+}
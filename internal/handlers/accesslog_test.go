@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"task-manager/pkg/logger"
+)
+
+// serveLogged serves req through a and returns the record it wrote, if any.
+func serveLogged(t *testing.T, a AccessLog, req *http.Request) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	req = req.WithContext(logger.NewContext(req.Context(), slog.New(slog.NewJSONHandler(&buf, nil))))
+	req.RemoteAddr = "192.0.2.7:51234"
+	req.Header.Set("User-Agent", "curl/8.5")
+	req.Header.Set(RequestIDHeader, "req-1")
+
+	handler := a.Record(RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("hello"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("expected the recorder to pass Flush on: %v", err)
+		}
+	})))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if buf.Len() == 0 {
+		return nil
+	}
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected one JSON record, got %q", buf.String())
+	}
+	return record
+}
+
+func TestAccessLog_Formats(t *testing.T) {
+	clf := `^192\.0\.2\.7 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /tasks\?limit=5 HTTP/1\.1" 200 5`
+	tests := []struct {
+		format AccessLogFormat
+		want   string
+	}{
+		{CommonLog, clf + `$`},
+		{CombinedLog, clf + ` "-" "curl/8\.5"$`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			record := serveLogged(t, AccessLog{Format: tt.format}, httptest.NewRequest(http.MethodGet, "/tasks?limit=5", nil))
+			if msg, _ := record["msg"].(string); !regexp.MustCompile(tt.want).MatchString(msg) {
+				t.Errorf("line %q does not match %s", msg, tt.want)
+			}
+			if _, ok := record["latency_ms"].(float64); !ok || record["request_id"] != "req-1" {
+				t.Errorf("expected the request ID and latency as fields, got %v", record)
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		record := serveLogged(t, AccessLog{Format: JSONLog}, httptest.NewRequest(http.MethodGet, "/missing", nil))
+		want := map[string]any{
+			"msg":         "request",
+			"request_id":  "req-1",
+			"method":      "GET",
+			"path":        "/missing",
+			"status":      float64(http.StatusNotFound),
+			"bytes":       float64(len("404 page not found\n")),
+			"remote_addr": "192.0.2.7:51234",
+			"user_agent":  "curl/8.5",
+		}
+		for key, value := range want {
+			if record[key] != value {
+				t.Errorf("%s = %v, want %v", key, record[key], value)
+			}
+		}
+		if _, ok := record["latency_ms"].(float64); !ok {
+			t.Errorf("expected latency_ms, got %v", record)
+		}
+	})
+}
+
+func TestAccessLog_Exclude(t *testing.T) {
+	a := AccessLog{Format: CommonLog, Exclude: []string{"/", "/debug/*"}}
+	for path, logged := range map[string]bool{"/": false, "/debug/logger": false, "/debug": true, "/tasks": true} {
+		if record := serveLogged(t, a, httptest.NewRequest(http.MethodGet, path, nil)); (record != nil) != logged {
+			t.Errorf("%s: logged = %v, want %v", path, record != nil, logged)
+		}
+	}
+}
+
+func TestParseAccessLogFormat(t *testing.T) {
+	if got, err := ParseAccessLogFormat(""); err != nil || got != CombinedLog {
+		t.Errorf(`ParseAccessLogFormat("") = %q, %v; want combined`, got, err)
+	}
+	if _, err := ParseAccessLogFormat("apache"); err == nil {
+		t.Errorf("expected an unknown format to be rejected")
+	}
+}
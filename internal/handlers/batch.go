@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"task-manager/internal/apperr"
+	"task-manager/internal/models"
+	service "task-manager/internal/services"
+	"task-manager/pkg/logger"
+)
+
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "best_effort"
+
+	maxBatchSize = 1000
+)
+
+type batchRequest struct {
+	// Mode is "atomic" (the default) or "best_effort".
+	Mode       string           `json:"mode"`
+	Operations []batchOperation `json:"operations"`
+}
+
+type batchOperation struct {
+	Op   service.BatchOp `json:"op"`
+	ID   int             `json:"id,omitempty"`
+	Task *models.Task    `json:"task,omitempty"`
+	// IfMatch is checked like the If-Match header of a single update or
+	// delete.
+	IfMatch string `json:"if_match,omitempty"`
+}
+
+type batchItemResult struct {
+	Status int          `json:"status"`
+	Task   *models.Task `json:"task,omitempty"`
+	Error  *Problem     `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchItemResult `json:"results"`
+}
+
+// Batch applies a list of create, update and delete operations in one
+// transaction. In atomic mode any failure rolls back the whole batch and the
+// response carries the status of the failing operation; in best-effort mode
+// the response is 200 and each result has its own status.
+func (h *Handlers) Batch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	switch {
+	case req.Mode != "" && req.Mode != batchAtomic && req.Mode != batchBestEffort:
+		h.fail(w, r, apperr.Invalid(models.NewValidationError("/mode", "mode must be %q or %q", batchAtomic, batchBestEffort)))
+		return
+	case len(req.Operations) == 0:
+		h.fail(w, r, apperr.Invalid(models.NewValidationError("/operations", "operations are required")))
+		return
+	case len(req.Operations) > maxBatchSize:
+		h.fail(w, r, apperr.Invalid(models.NewValidationError("/operations", "a batch holds at most %d operations", maxBatchSize)))
+		return
+	}
+
+	items := make([]service.BatchItem, len(req.Operations))
+	for i, op := range req.Operations {
+		items[i] = service.BatchItem{Op: op.Op, ID: op.ID}
+		if op.Task != nil {
+			if err := h.checkServerFields(op.Task, fmt.Sprintf("/operations/%d/task", i)); err != nil {
+				h.fail(w, r, err)
+				return
+			}
+			items[i].Task = *op.Task
+		}
+		if op.IfMatch != "" {
+			items[i].Precondition = ifMatch(op.IfMatch)
+		}
+	}
+
+	atomic := req.Mode != batchBestEffort
+	results, err := h.taskSvc.Batch(r.Context(), items, atomic)
+	var batchErr *service.BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		h.fail(w, r, err)
+		return
+	}
+
+	resp := batchResponse{Results: make([]batchItemResult, len(results))}
+	for i, result := range results {
+		resp.Results[i] = batchItemResultOf(items[i].Op, result)
+		if result.Err != nil && resp.Results[i].Status == http.StatusInternalServerError {
+			logger.ErrorContext(r.Context(), "batch operation failed", "index", i, "error", result.Err)
+		}
+	}
+	status := http.StatusOK
+	if batchErr != nil {
+		status = resp.Results[batchErr.Index].Status
+	}
+	h.writeJSON(w, status, resp)
+}
+
+func batchItemResultOf(op service.BatchOp, result service.BatchResult) batchItemResult {
+	switch {
+	case result.Err != nil:
+		p := problemOf(result.Err, "")
+		if errors.Is(result.Err, service.ErrBatchAborted) {
+			p.Status, p.Title = http.StatusFailedDependency, http.StatusText(http.StatusFailedDependency)
+		}
+		return batchItemResult{Status: p.Status, Error: &p}
+	case op == service.BatchCreate:
+		return batchItemResult{Status: http.StatusCreated, Task: &result.Task}
+	case op == service.BatchDelete:
+		return batchItemResult{Status: http.StatusNoContent}
+	default:
+		return batchItemResult{Status: http.StatusOK, Task: &result.Task}
+	}
+}
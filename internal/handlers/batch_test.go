@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"task-manager/internal/apperr"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+)
+
+func decodeBatch(t *testing.T, w *httptest.ResponseRecorder) batchResponse {
+	t.Helper()
+	var resp batchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("cannot decode response body: %v", err)
+	}
+	return resp
+}
+
+func TestBatch_BestEffort(t *testing.T) {
+	var gotAtomic bool
+	var gotItems []services.BatchItem
+	mockSvc := &MockTaskService{
+		BatchFunc: func(ctx context.Context, items []services.BatchItem, atomic bool) ([]services.BatchResult, error) {
+			gotAtomic, gotItems = atomic, items
+			return []services.BatchResult{
+				{Task: models.Task{ID: 5, Title: "New"}},
+				{Err: services.ErrTaskNotFound},
+				{Err: items[2].Precondition(models.Task{ID: 2, Version: 3})},
+				{},
+			}, nil
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	body := `{"mode":"best_effort","operations":[
+		{"op":"create","task":{"title":"New"}},
+		{"op":"update","id":9,"task":{"title":"Gone"}},
+		{"op":"update","id":2,"task":{"title":"Stale"},"if_match":"\"2\""},
+		{"op":"delete","id":3}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/tasks/batch", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.Batch(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if gotAtomic || len(gotItems) != 4 || gotItems[1].ID != 9 || gotItems[0].Task.Title != "New" {
+		t.Errorf("unexpected service call: atomic=%v items=%+v", gotAtomic, gotItems)
+	}
+	resp := decodeBatch(t, w)
+	want := []int{http.StatusCreated, http.StatusNotFound, http.StatusPreconditionFailed, http.StatusNoContent}
+	for i, result := range resp.Results {
+		if result.Status != want[i] {
+			t.Errorf("result %d: expected status %d, got %d", i, want[i], result.Status)
+		}
+	}
+}
+
+func TestBatch_AtomicFailure(t *testing.T) {
+	mockSvc := &MockTaskService{
+		BatchFunc: func(ctx context.Context, items []services.BatchItem, atomic bool) ([]services.BatchResult, error) {
+			if !atomic {
+				t.Errorf("expected atomic mode by default")
+			}
+			transition := apperr.Wrap(&services.TransitionError{From: models.StatusDone, To: models.StatusBlocked}, apperr.Conflict, "invalid_transition")
+			return []services.BatchResult{
+				{Err: services.ErrBatchAborted},
+				{Err: transition},
+			}, &services.BatchError{Index: 1, Err: transition}
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	body := `{"operations":[{"op":"create","task":{"title":"New"}},{"op":"update","id":1,"task":{"title":"T","status":"blocked"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/tasks/batch", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.Batch(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+	resp := decodeBatch(t, w)
+	if resp.Results[0].Status != http.StatusFailedDependency || resp.Results[1].Status != http.StatusConflict {
+		t.Errorf("unexpected results %+v", resp.Results)
+	}
+}
+
+func TestBatch_StorageError(t *testing.T) {
+	mockSvc := &MockTaskService{
+		BatchFunc: func(ctx context.Context, items []services.BatchItem, atomic bool) ([]services.BatchResult, error) {
+			return nil, errors.New("disk full")
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks/batch", strings.NewReader(`{"operations":[{"op":"delete","id":1}]}`))
+	w := httptest.NewRecorder()
+	h.Batch(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestBatch_InvalidRequest(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	for _, body := range []string{
+		`{"operations":[]}`,
+		`{"mode":"sometimes","operations":[{"op":"delete","id":1}]}`,
+		`not json`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/tasks/batch", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.Batch(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, w.Code)
+		}
+	}
+}
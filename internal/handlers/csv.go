@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-manager/internal/models"
+)
+
+// csvInputColumns are the columns a CSV upload may set; the rest of a task
+// is owned by the server.
+var csvInputColumns = []string{"title", "description", "status", "priority", "due_at"}
+
+// encodeCSV writes a header row followed by one row per task. Columns follow
+// models.TaskFields, or fields when given; unset values are empty cells.
+func encodeCSV(tasks []models.Task, fields []string) ([]byte, error) {
+	if len(fields) == 0 {
+		fields = models.TaskFields
+	}
+	rows, err := selectFields(tasks, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Write(fields)
+	record := make([]string, len(fields))
+	for _, row := range rows {
+		for i, field := range fields {
+			record[i], err = csvCell(row[field])
+			if err != nil {
+				return nil, err
+			}
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return buf.Bytes(), cw.Error()
+}
+
+func csvCell(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	if raw[0] != '"' {
+		return string(raw), nil
+	}
+	var s string
+	err := json.Unmarshal(raw, &s)
+	return escapeFormula(s), err
+}
+
+// formulaPrefixes start a cell that spreadsheets evaluate as a formula.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormula quotes a text cell that a spreadsheet would otherwise run as
+// a formula, the way spreadsheets themselves mark text.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// unescapeFormula undoes escapeFormula, so an export can be uploaded again.
+func unescapeFormula(s string) string {
+	if rest, ok := strings.CutPrefix(s, "'"); ok && escapeFormula(rest) == s {
+		return rest
+	}
+	return s
+}
+
+// decodeCSV reads tasks from a CSV document whose header row names the
+// columns and checks them with v. Every invalid row is reported, prefixed
+// with its line number.
+func decodeCSV(r io.Reader, v *models.Validator) ([]models.Task, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv: missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range header {
+		if !slices.Contains(csvInputColumns, column) {
+			return nil, fmt.Errorf("csv: unknown column %q, expected some of %v", column, csvInputColumns)
+		}
+	}
+
+	var tasks []models.Task
+	var errs []error
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		task, err := csvTask(header, record)
+		if err == nil {
+			err = v.Validate(&task)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(tasks) == 0 {
+		return nil, errors.New("csv: no tasks")
+	}
+	return tasks, nil
+}
+
+func csvTask(header, record []string) (models.Task, error) {
+	var task models.Task
+	for i, value := range record {
+		switch header[i] {
+		case "title":
+			task.Title = unescapeFormula(value)
+		case "description":
+			task.Description = unescapeFormula(value)
+		case "status":
+			task.Status = models.Status(value)
+		case "priority":
+			if value == "" {
+				continue
+			}
+			priority, err := strconv.Atoi(value)
+			if err != nil {
+				return task, fmt.Errorf("priority must be an integer, got %q", value)
+			}
+			task.Priority = priority
+		case "due_at":
+			if value == "" {
+				continue
+			}
+			due, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return task, fmt.Errorf("due_at must be an RFC 3339 time, got %q", value)
+			}
+			task.DueAt = &due
+		}
+	}
+	return task, nil
+}
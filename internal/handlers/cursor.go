@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"task-manager/internal/models"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// cursor is the signed content of a pagination token: the position of the
+// last task on the previous page and a digest of the query it belongs to,
+// so a token cannot be replayed against a different filter or sort.
+type cursor struct {
+	Query string          `json:"q"`
+	After json.RawMessage `json:"a"`
+}
+
+// queryDigest identifies the selection and order of a query.
+func queryDigest(query models.TaskQuery) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "overdue=%t\n", query.Overdue)
+	for _, f := range query.Filters {
+		fmt.Fprintf(&b, "filter=%s\n", f)
+	}
+	for _, key := range query.Sort {
+		fmt.Fprintf(&b, "sort=%s,%t\n", key.Field, key.Desc)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// encodeCursor returns an opaque token resuming query after task. Only the
+// task fields the query sorts on are kept.
+func (h *Handlers) encodeCursor(query models.TaskQuery, task models.Task) (string, error) {
+	fields := []string{"id"}
+	for _, key := range query.Sort {
+		fields = append(fields, key.Field)
+	}
+	position, err := selectFields([]models.Task{task}, fields)
+	if err != nil {
+		return "", err
+	}
+	after, err := json.Marshal(position[0])
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(cursor{Query: queryDigest(query), After: after})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(h.sign(payload)), nil
+}
+
+// decodeCursor verifies a token and returns the position it resumes after.
+func (h *Handlers) decodeCursor(token string, query models.TaskQuery) (*models.Task, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, h.sign(payload)) {
+		return nil, errInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, errInvalidCursor
+	}
+	if c.Query != queryDigest(query) {
+		return nil, fmt.Errorf("%w: it belongs to a different filter or sort", errInvalidCursor)
+	}
+	var after models.Task
+	if err := json.Unmarshal(c.After, &after); err != nil {
+		return nil, errInvalidCursor
+	}
+	return &after, nil
+}
+
+func (h *Handlers) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, h.cursorKey)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
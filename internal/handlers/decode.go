@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-manager/internal/apperr"
+	"task-manager/internal/models"
+)
+
+// DefaultMaxBodyBytes caps JSON request bodies unless WithMaxBodyBytes says
+// otherwise.
+const DefaultMaxBodyBytes = 1 << 20
+
+// ServerFields decides what happens to the members of a task the server
+// owns (id, version and the timestamps) when a client sends them.
+type ServerFields string
+
+const (
+	// IgnoreServerFields drops them; the server sets its own values.
+	IgnoreServerFields ServerFields = "ignore"
+	// RejectServerFields answers 400 naming each of them.
+	RejectServerFields ServerFields = "reject"
+)
+
+// ParseServerFields reads a ServerFields policy; empty means ignore.
+func ParseServerFields(s string) (ServerFields, error) {
+	switch ServerFields(s) {
+	case "", IgnoreServerFields:
+		return IgnoreServerFields, nil
+	case RejectServerFields:
+		return RejectServerFields, nil
+	default:
+		return "", fmt.Errorf("server fields policy must be %q or %q, got %q", IgnoreServerFields, RejectServerFields, s)
+	}
+}
+
+// limitBody caps the request body at the configured size.
+func (h *Handlers) limitBody(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBodyBytes)
+}
+
+// decodeJSON reads the request body into v. The body must be JSON, or
+// untyped; fit the size limit; have no members v does not know; and hold a
+// single value.
+func (h *Handlers) decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	if contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); contentType != "" && contentType != "application/json" {
+		return apperr.New(apperr.Unsupported, "unsupported_media_type", fmt.Sprintf("unsupported content type %q, use application/json", contentType))
+	}
+	h.limitBody(w, r)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return decodeError(err)
+		}
+		return apperr.New(apperr.BadRequest, "malformed_body", "request body must hold a single JSON value")
+	}
+	return nil
+}
+
+// decodeTask reads a task from the request body and applies the server
+// fields policy.
+func (h *Handlers) decodeTask(w http.ResponseWriter, r *http.Request) (models.Task, error) {
+	var task models.Task
+	if err := h.decodeJSON(w, r, &task); err != nil {
+		return models.Task{}, err
+	}
+	return task, h.checkServerFields(&task, "")
+}
+
+// checkServerFields clears the server-owned members of task, or reports them
+// under prefix when they are rejected.
+func (h *Handlers) checkServerFields(task *models.Task, prefix string) error {
+	if err := h.rejectServerFields(setServerFields(task), prefix); err != nil {
+		return err
+	}
+	clearServerFields(task)
+	return nil
+}
+
+// clearServerFields drops the server-owned members of task.
+func clearServerFields(task *models.Task) {
+	task.ID, task.Version = 0, 0
+	task.CreatedAt, task.UpdatedAt = time.Time{}, time.Time{}
+	task.StartedAt, task.CompletedAt = nil, nil
+}
+
+// rejectServerFields reports the server-owned fields a client sent, under
+// prefix, when the policy rejects them.
+func (h *Handlers) rejectServerFields(fields []string, prefix string) error {
+	if h.serverFields != RejectServerFields {
+		return nil
+	}
+	var violations []models.Violation
+	for _, field := range fields {
+		violations = append(violations, models.Violation{
+			Pointer: prefix + "/" + field,
+			Message: field + " is set by the server",
+		})
+	}
+	if len(violations) > 0 {
+		return apperr.Invalid(&models.ValidationError{Violations: violations})
+	}
+	return nil
+}
+
+// decodePatched reads the document a patch turned the encoding of current
+// into. It is held to the rules of a request body: members a task does not
+// have are an error, and so are changes to server-owned members when they
+// are rejected; otherwise those keep their current values.
+func (h *Handlers) decodePatched(current models.Task, patched []byte) (models.Task, error) {
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	var task models.Task
+	if err := dec.Decode(&task); err != nil {
+		return models.Task{}, decodeError(err)
+	}
+	if err := h.rejectServerFields(changedServerFields(&current, &task), ""); err != nil {
+		return models.Task{}, err
+	}
+	task.ID, task.Version = current.ID, current.Version
+	task.CreatedAt, task.UpdatedAt = current.CreatedAt, current.UpdatedAt
+	task.StartedAt, task.CompletedAt = current.StartedAt, current.CompletedAt
+	return task, nil
+}
+
+// changedServerFields names the server-owned members that differ between a
+// task and its patched version.
+func changedServerFields(current, patched *models.Task) []string {
+	var fields []string
+	if patched.ID != current.ID {
+		fields = append(fields, "id")
+	}
+	if patched.Version != current.Version {
+		fields = append(fields, "version")
+	}
+	if !patched.CreatedAt.Equal(current.CreatedAt) {
+		fields = append(fields, "created_at")
+	}
+	if !patched.UpdatedAt.Equal(current.UpdatedAt) {
+		fields = append(fields, "updated_at")
+	}
+	if !equalTime(patched.StartedAt, current.StartedAt) {
+		fields = append(fields, "started_at")
+	}
+	if !equalTime(patched.CompletedAt, current.CompletedAt) {
+		fields = append(fields, "completed_at")
+	}
+	return fields
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// setServerFields names the server-owned members task has values for.
+func setServerFields(task *models.Task) []string {
+	var fields []string
+	if task.ID != 0 {
+		fields = append(fields, "id")
+	}
+	if task.Version != 0 {
+		fields = append(fields, "version")
+	}
+	if !task.CreatedAt.IsZero() {
+		fields = append(fields, "created_at")
+	}
+	if !task.UpdatedAt.IsZero() {
+		fields = append(fields, "updated_at")
+	}
+	if task.StartedAt != nil {
+		fields = append(fields, "started_at")
+	}
+	if task.CompletedAt != nil {
+		fields = append(fields, "completed_at")
+	}
+	return fields
+}
+
+// unknownField extracts the member name from the decoder's error for a
+// member DisallowUnknownFields refused, which has no error type of its own.
+func unknownField(err error) (string, bool) {
+	name, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return "", false
+	}
+	if unquoted, err := strconv.Unquote(name); err == nil {
+		name = unquoted
+	}
+	return name, true
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"task-manager/internal/models"
+)
+
+func TestDecode_Strict(t *testing.T) {
+	tests := []struct {
+		name, contentType, body string
+		status                  int
+		code                    string
+	}{
+		{"unknown field", "application/json", `{"title":"Task","owner":"me"}`, http.StatusBadRequest, "unknown_field"},
+		{"trailing value", "application/json", `{"title":"Task"}{"title":"Again"}`, http.StatusBadRequest, "malformed_body"},
+		{"trailing garbage", "", `{"title":"Task"} x`, http.StatusBadRequest, "malformed_body"},
+		{"wrong content type", "text/plain", `{"title":"Task"}`, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{"too large", "application/json", `{"title":"` + strings.Repeat("x", 64) + `"}`, http.StatusRequestEntityTooLarge, "body_too_large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandlers(&MockTaskService{}, WithMaxBodyBytes(48))
+
+			req := httptest.NewRequest(http.MethodPut, "/tasks/1", strings.NewReader(tt.body))
+			req.SetPathValue("id", "1")
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			h.UpdateTask(w, req)
+
+			p := decodeProblem(t, w)
+			if w.Code != tt.status || p.Code != tt.code {
+				t.Fatalf("expected %d %s, got %d %+v", tt.status, tt.code, w.Code, p)
+			}
+		})
+	}
+}
+
+func TestDecode_ServerFieldsIgnored(t *testing.T) {
+	var got models.Task
+	h := NewHandlers(&MockTaskService{
+		CreateTaskFunc: func(ctx context.Context, task models.Task) (models.Task, error) {
+			got = task
+			task.ID = 1
+			return task, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"id":42,"version":7,"title":"Task"}`))
+	w := httptest.NewRecorder()
+	h.CreateTask(w, req)
+
+	if w.Code != http.StatusCreated || got.ID != 0 || got.Version != 0 {
+		t.Fatalf("expected id and version to be dropped, got %d %+v", w.Code, got)
+	}
+}
+
+func TestDecode_ServerFieldsRejected(t *testing.T) {
+	h := NewHandlers(&MockTaskService{}, WithServerFields(RejectServerFields))
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"id":42,"title":"Task","created_at":"2024-01-01T00:00:00Z"}`))
+	w := httptest.NewRecorder()
+	h.CreateTask(w, req)
+
+	p := decodeProblem(t, w)
+	if w.Code != http.StatusBadRequest || len(p.Errors) != 2 || p.Errors[0].Pointer != "/id" || p.Errors[1].Pointer != "/created_at" {
+		t.Fatalf("expected /id and /created_at to be rejected, got %d %+v", w.Code, p)
+	}
+}
+
+func TestDecode_StrictPatch(t *testing.T) {
+	tests := []struct {
+		name, contentType, body string
+		policy                  ServerFields
+		code, pointer           string
+	}{
+		{"merge patch unknown field", "application/merge-patch+json", `{"titel":"b"}`, IgnoreServerFields, "unknown_field", ""},
+		{"json patch unknown field", "application/json-patch+json", `[{"op":"add","path":"/bogus","value":1}]`, IgnoreServerFields, "unknown_field", ""},
+		{"server field rejected", "application/json-patch+json", `[{"op":"replace","path":"/version","value":99}]`, RejectServerFields, "validation_failed", "/version"},
+		{"server field ignored", "application/json-patch+json", `[{"op":"replace","path":"/version","value":99}]`, IgnoreServerFields, "", ""},
+		{"no change", "application/merge-patch+json", `{"title":"Title"}`, IgnoreServerFields, "", ""},
+		{"test only", "application/json-patch+json", `[{"op":"test","path":"/status","value":"todo"}]`, IgnoreServerFields, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandlers(&MockTaskService{
+				UpdateTaskFunc: updateWith(models.Task{ID: 1, Version: 3, Title: "Title", Status: models.StatusTodo}),
+			}, WithServerFields(tt.policy))
+
+			req := httptest.NewRequest(http.MethodPatch, "/tasks/1", strings.NewReader(tt.body))
+			req.SetPathValue("id", "1")
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			h.PatchTask(w, req)
+
+			if tt.code == "" {
+				var task models.Task
+				json.NewDecoder(w.Body).Decode(&task)
+				if w.Code != http.StatusOK || task.Title != "Title" || task.Version != 3 {
+					t.Fatalf("expected 200 with the task unchanged, got %d %+v", w.Code, task)
+				}
+				return
+			}
+			p := decodeProblem(t, w)
+			if w.Code != http.StatusBadRequest || p.Code != tt.code {
+				t.Fatalf("expected 400 %s, got %d %+v", tt.code, w.Code, p)
+			}
+			if tt.pointer != "" && (len(p.Errors) != 1 || p.Errors[0].Pointer != tt.pointer) {
+				t.Errorf("expected %s to be named, got %+v", tt.pointer, p.Errors)
+			}
+		})
+	}
+}
@@ -2,108 +2,380 @@ package handlers
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
-	"strconv"
 
+	"task-manager/internal/apperr"
 	"task-manager/internal/models"
+	"task-manager/internal/patch"
 	service "task-manager/internal/services"
 )
 
 type TaskService interface {
 	CreateTask(ctx context.Context, task models.Task) (models.Task, error)
 	GetTask(ctx context.Context, id int) (models.Task, error)
-	GetTasks(ctx context.Context) ([]models.Task, error)
-	DeleteTask(ctx context.Context, id int) error
+	GetTasks(ctx context.Context, query models.TaskQuery) ([]models.Task, error)
+	ExportTasks(ctx context.Context, fn func(models.Task) error) error
+	UpdateTask(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error)
+	DeleteTask(ctx context.Context, id int, precondition func(models.Task) error) error
+	Batch(ctx context.Context, items []service.BatchItem, atomic bool) ([]service.BatchResult, error)
 }
 
+var acceptPatch = patch.JSONPatchContentType + ", " + patch.MergePatchContentType
+
 type Handlers struct {
-	taskSvc TaskService
+	taskSvc    TaskService
+	cursorKey  []byte
+	serializer Serializer
+	basePath   string
+	validator  *models.Validator
+
+	maxBodyBytes int64
+	serverFields ServerFields
+}
+
+type Option func(*Handlers)
+
+// WithCursorKey sets the HMAC key that signs pagination cursors. Without it a
+// random key is used, so cursors do not survive a restart.
+func WithCursorKey(key []byte) Option {
+	return func(h *Handlers) {
+		h.cursorKey = key
+	}
+}
+
+// WithSerializer sets the API version the handlers answer in; the default
+// is V1.
+func WithSerializer(s Serializer) Option {
+	return func(h *Handlers) {
+		h.serializer = s
+	}
+}
+
+// WithBasePath sets the prefix, such as "/v2", the handlers are mounted
+// under. It is put in front of the links they return.
+func WithBasePath(prefix string) Option {
+	return func(h *Handlers) {
+		h.basePath = prefix
+	}
+}
+
+// WithValidator sets the rules request bodies are checked against before
+// they reach the service; the default is models.DefaultValidator.
+func WithValidator(v *models.Validator) Option {
+	return func(h *Handlers) {
+		h.validator = v
+	}
+}
+
+// WithMaxBodyBytes caps JSON request bodies at n bytes; larger ones get
+// 413 Content Too Large.
+func WithMaxBodyBytes(n int64) Option {
+	return func(h *Handlers) {
+		h.maxBodyBytes = n
+	}
+}
+
+// WithServerFields sets what happens to server-owned task members sent by
+// clients.
+func WithServerFields(policy ServerFields) Option {
+	return func(h *Handlers) {
+		h.serverFields = policy
+	}
 }
 
-func NewHandlers(services TaskService) *Handlers {
-	return &Handlers{
-		taskSvc: services,
+func NewHandlers(services TaskService, opts ...Option) *Handlers {
+	h := &Handlers{
+		taskSvc:      services,
+		serializer:   V1,
+		validator:    models.DefaultValidator,
+		maxBodyBytes: DefaultMaxBodyBytes,
+		serverFields: IgnoreServerFields,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if len(h.cursorKey) == 0 {
+		h.cursorKey = make([]byte, 32)
+		rand.Read(h.cursorKey)
+	}
+	return h
 }
 
 func (h *Handlers) CreateTask(w http.ResponseWriter, r *http.Request) {
-	var task models.Task
-	err := json.NewDecoder(r.Body).Decode(&task)
+	f, ok := h.acceptable(w, r)
+	if !ok {
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch contentType {
+	case "", "application/json":
+	case string(formatCSV):
+		h.createTasksFromCSV(w, r, f)
+		return
+	default:
+		h.fail(w, r, apperr.New(apperr.Unsupported, "unsupported_media_type", fmt.Sprintf("unsupported content type %q, use application/json or text/csv", contentType)))
+		return
+	}
+
+	task, err := h.decodeTask(w, r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.fail(w, r, err)
 		return
 	}
 
-	if err := task.Validate(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := h.validator.Validate(&task); err != nil {
+		h.fail(w, r, apperr.Invalid(err))
 		return
 	}
 
 	createdTask, err := h.taskSvc.CreateTask(r.Context(), task)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.fail(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(createdTask)
+	w.Header().Set("Location", h.taskPath(createdTask.ID))
+	w.Header().Set("ETag", etag(createdTask))
+	h.renderTask(w, r, http.StatusCreated, f, createdTask)
 }
 
-func (h *Handlers) GetTask(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Query().Get("id")
+// createTasksFromCSV creates a task from every row of a CSV upload, in one
+// atomic batch: nothing is created unless every row is valid and stored.
+func (h *Handlers) createTasksFromCSV(w http.ResponseWriter, r *http.Request, f format) {
+	h.limitBody(w, r)
+	tasks, err := decodeCSV(r.Body, h.validator)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.fail(w, r, decodeError(err))
+			return
+		}
+		h.fail(w, r, apperr.Wrap(err, apperr.BadRequest, "invalid_csv"))
+		return
+	}
 
-	id, err := strconv.Atoi(idStr)
+	items := make([]service.BatchItem, len(tasks))
+	for i, task := range tasks {
+		items[i] = service.BatchItem{Op: service.BatchCreate, Task: task}
+	}
+	results, err := h.taskSvc.Batch(r.Context(), items, true)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.fail(w, r, err)
+		return
+	}
+	created := make([]models.Task, len(results))
+	for i, result := range results {
+		created[i] = result.Task
+	}
+	h.render(w, r, http.StatusCreated, f, created, Meta{}, created, nil)
+}
+
+func (h *Handlers) GetTask(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.acceptable(w, r)
+	if !ok {
+		return
+	}
+	id, err := taskID(r)
+	if err != nil {
+		h.fail(w, r, err)
 		return
 	}
 
 	task, err := h.taskSvc.GetTask(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, service.ErrTaskNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.fail(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(task)
+	w.Header().Set("ETag", etag(task))
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && matchETag(ifNoneMatch, etag(task), true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.renderTask(w, r, http.StatusOK, f, task)
 }
 
 func (h *Handlers) GetTasks(w http.ResponseWriter, r *http.Request) {
-	tasks, err := h.taskSvc.GetTasks(r.Context())
+	f, ok := h.acceptable(w, r)
+	if !ok {
+		return
+	}
+	params, err := parseListParams(r.URL.RawQuery)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.fail(w, r, apperr.Wrap(err, apperr.BadRequest, "invalid_query"))
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(tasks)
+	query := params.query
+	if params.after != "" {
+		query.After, err = h.decodeCursor(params.after, query)
+		if err != nil {
+			h.fail(w, r, apperr.Wrap(err, apperr.BadRequest, "invalid_cursor"))
+			return
+		}
+	}
+	// One extra task tells whether there is a next page.
+	if params.paged() {
+		query.Limit = params.limit + 1
+	}
+
+	tasks, err := h.taskSvc.GetTasks(r.Context(), query)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	var next string
+	if params.paged() && len(tasks) > params.limit {
+		tasks = tasks[:params.limit]
+		token, err := h.encodeCursor(query, tasks[len(tasks)-1])
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		next = nextPageURL(h.basePath, r.URL, token)
+		w.Header().Set("Link", "<"+next+`>; rel="next"`)
+	}
+
+	var body any = tasks
+	if len(params.fields) > 0 {
+		body, err = selectFields(tasks, params.fields)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+	}
+	var meta Meta
+	if params.paged() {
+		meta.Page = &PageMeta{Limit: params.limit, Next: next}
+	}
+
+	h.render(w, r, http.StatusOK, f, body, meta, tasks, params.fields)
 }
 
-func (h *Handlers) DeleteTask(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Query().Get("id")
+func (h *Handlers) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	id, err := taskID(r)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	task, err := h.decodeTask(w, r)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err := h.validator.Validate(&task); err != nil {
+		h.fail(w, r, apperr.Invalid(err))
+		return
+	}
+
+	precondition := writePreconditions(r)
+	updatedTask, err := h.taskSvc.UpdateTask(r.Context(), id, func(current models.Task) (models.Task, error) {
+		if precondition != nil {
+			if err := precondition(current); err != nil {
+				return models.Task{}, err
+			}
+		}
+		return task, nil
+	})
+	h.writeUpdateResult(w, r, updatedTask, err)
+}
 
-	id, err := strconv.Atoi(idStr)
+func (h *Handlers) PatchTask(w http.ResponseWriter, r *http.Request) {
+	id, err := taskID(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.fail(w, r, err)
 		return
 	}
 
-	err = h.taskSvc.DeleteTask(r.Context(), id)
+	var apply func(doc, patch []byte) ([]byte, error)
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch contentType {
+	case patch.JSONPatchContentType:
+		apply = patch.JSONPatch
+	case patch.MergePatchContentType, "application/json":
+		apply = patch.MergePatch
+	default:
+		w.Header().Set("Accept-Patch", acceptPatch)
+		h.fail(w, r, apperr.New(apperr.Unsupported, "unsupported_media_type", fmt.Sprintf("unsupported patch content type %q", contentType)))
+		return
+	}
+
+	h.limitBody(w, r)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		if errors.Is(err, service.ErrTaskNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.fail(w, r, decodeError(err))
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.fail(w, r, apperr.New(apperr.BadRequest, "unreadable_body", "request body could not be read"))
+		return
+	}
+
+	// The patch is applied inside the repository transaction, so a failed
+	// "test" operation or an invalid result leaves the stored task untouched.
+	precondition := writePreconditions(r)
+	updatedTask, err := h.taskSvc.UpdateTask(r.Context(), id, func(current models.Task) (models.Task, error) {
+		if precondition != nil {
+			if err := precondition(current); err != nil {
+				return models.Task{}, err
+			}
+		}
+		doc, err := json.Marshal(current)
+		if err != nil {
+			return models.Task{}, err
+		}
+		patched, err := apply(doc, body)
+		if err != nil {
+			if errors.Is(err, patch.ErrTestFailed) {
+				return models.Task{}, apperr.Wrap(err, apperr.Conflict, "patch_test_failed")
+			}
+			return models.Task{}, apperr.Wrap(err, apperr.BadRequest, "invalid_patch")
+		}
+		task, err := h.decodePatched(current, patched)
+		if err != nil {
+			return models.Task{}, err
+		}
+		if err := h.validator.Validate(&task); err != nil {
+			return models.Task{}, apperr.Invalid(err)
+		}
+		return task, nil
+	})
+	h.writeUpdateResult(w, r, updatedTask, err)
+}
+
+func (h *Handlers) writeUpdateResult(w http.ResponseWriter, r *http.Request, task models.Task, err error) {
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(task))
+	h.writeJSON(w, http.StatusOK, task)
+}
+
+func (h *Handlers) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	id, err := taskID(r)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	err = h.taskSvc.DeleteTask(r.Context(), id, writePreconditions(r))
+	if err != nil {
+		h.fail(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Task deleted successfully"))
+	h.serializer.Deleted(w)
 }
@@ -8,16 +8,20 @@ import (
 	"strings"
 	"testing"
 
+	"task-manager/internal/apperr"
 	"task-manager/internal/models"
 	"task-manager/internal/services"
 )
 
 // Мок для сервиса
 type MockTaskService struct {
-	CreateTaskFunc func(ctx context.Context, task models.Task) (models.Task, error)
-	GetTaskFunc    func(ctx context.Context, id int) (models.Task, error)
-	GetTasksFunc   func(ctx context.Context) ([]models.Task, error)
-	DeleteTaskFunc func(ctx context.Context, id int) error
+	CreateTaskFunc  func(ctx context.Context, task models.Task) (models.Task, error)
+	GetTaskFunc     func(ctx context.Context, id int) (models.Task, error)
+	GetTasksFunc    func(ctx context.Context, query models.TaskQuery) ([]models.Task, error)
+	ExportTasksFunc func(ctx context.Context, fn func(models.Task) error) error
+	UpdateTaskFunc  func(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error)
+	DeleteTaskFunc  func(ctx context.Context, id int, precondition func(models.Task) error) error
+	BatchFunc       func(ctx context.Context, items []services.BatchItem, atomic bool) ([]services.BatchResult, error)
 }
 
 func (m *MockTaskService) CreateTask(ctx context.Context, task models.Task) (models.Task, error) {
@@ -28,12 +32,24 @@ func (m *MockTaskService) GetTask(ctx context.Context, id int) (models.Task, err
 	return m.GetTaskFunc(ctx, id)
 }
 
-func (m *MockTaskService) GetTasks(ctx context.Context) ([]models.Task, error) {
-	return m.GetTasksFunc(ctx)
+func (m *MockTaskService) GetTasks(ctx context.Context, query models.TaskQuery) ([]models.Task, error) {
+	return m.GetTasksFunc(ctx, query)
 }
 
-func (m *MockTaskService) DeleteTask(ctx context.Context, id int) error {
-	return m.DeleteTaskFunc(ctx, id)
+func (m *MockTaskService) ExportTasks(ctx context.Context, fn func(models.Task) error) error {
+	return m.ExportTasksFunc(ctx, fn)
+}
+
+func (m *MockTaskService) UpdateTask(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error) {
+	return m.UpdateTaskFunc(ctx, id, update)
+}
+
+func (m *MockTaskService) DeleteTask(ctx context.Context, id int, precondition func(models.Task) error) error {
+	return m.DeleteTaskFunc(ctx, id, precondition)
+}
+
+func (m *MockTaskService) Batch(ctx context.Context, items []services.BatchItem, atomic bool) ([]services.BatchResult, error) {
+	return m.BatchFunc(ctx, items, atomic)
 }
 
 // Тест CreateTask - успешное создание задачи
@@ -75,7 +91,7 @@ func TestGetTasks_Success(t *testing.T) {
 		{ID: 2, Title: "Task 2"},
 	}
 	mockSvc := &MockTaskService{
-		GetTasksFunc: func(ctx context.Context) ([]models.Task, error) {
+		GetTasksFunc: func(ctx context.Context, query models.TaskQuery) ([]models.Task, error) {
 			return mockTasks, nil
 		},
 	}
@@ -126,7 +142,7 @@ func TestGetTask_Success(t *testing.T) {
 
 func TestDeleteTask_Success(t *testing.T) {
 	mockSvc := &MockTaskService{
-		DeleteTaskFunc: func(ctx context.Context, id int) error {
+		DeleteTaskFunc: func(ctx context.Context, id int, precondition func(models.Task) error) error {
 			return nil
 		},
 	}
@@ -147,7 +163,7 @@ func TestDeleteTask_Success(t *testing.T) {
 
 func TestDeleteTask_NotFound(t *testing.T) {
 	mockSvc := &MockTaskService{
-		DeleteTaskFunc: func(ctx context.Context, id int) error {
+		DeleteTaskFunc: func(ctx context.Context, id int, precondition func(models.Task) error) error {
 			return services.ErrTaskNotFound
 		},
 	}
@@ -182,3 +198,339 @@ func TestDeleteTask_BadRequest(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
 	}
 }
+
+// updateWith returns an UpdateTaskFunc that applies the update to existing,
+// the way the repository does, or reports ErrTaskNotFound for other ids.
+func updateWith(existing models.Task) func(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error) {
+	return func(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error) {
+		if id != existing.ID {
+			return models.Task{}, services.ErrTaskNotFound
+		}
+		task, err := update(existing)
+		if err != nil {
+			return models.Task{}, err
+		}
+		task.ID = id
+		return task, nil
+	}
+}
+
+func TestUpdateTask_Success(t *testing.T) {
+	mockSvc := &MockTaskService{
+		UpdateTaskFunc: updateWith(models.Task{ID: 1, Title: "Old", Description: "Desc"}),
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodPut, "/tasks?id=1", strings.NewReader(`{"title":"New"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.UpdateTask(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var task models.Task
+	json.NewDecoder(resp.Body).Decode(&task)
+	if task.ID != 1 || task.Title != "New" || task.Description != "" {
+		t.Errorf("expected full replacement, got %+v", task)
+	}
+}
+
+func TestUpdateTask_Invalid(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodPut, "/tasks?id=1", strings.NewReader(`{"title":""}`))
+	w := httptest.NewRecorder()
+
+	h.UpdateTask(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUpdateTask_NotFound(t *testing.T) {
+	mockSvc := &MockTaskService{
+		UpdateTaskFunc: updateWith(models.Task{ID: 1, Title: "Old"}),
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodPut, "/tasks?id=2", strings.NewReader(`{"title":"New"}`))
+	w := httptest.NewRecorder()
+
+	h.UpdateTask(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestPatchTask_MergePatch(t *testing.T) {
+	mockSvc := &MockTaskService{
+		UpdateTaskFunc: updateWith(models.Task{ID: 1, Title: "Tpyo", Description: "Desc"}),
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodPatch, "/tasks?id=1", strings.NewReader(`{"title":"Typo"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := httptest.NewRecorder()
+
+	h.PatchTask(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	var task models.Task
+	json.NewDecoder(w.Body).Decode(&task)
+	if task.Title != "Typo" || task.Description != "Desc" {
+		t.Errorf("expected only the title to change, got %+v", task)
+	}
+}
+
+func TestPatchTask_RemovingTitleIsInvalid(t *testing.T) {
+	mockSvc := &MockTaskService{
+		UpdateTaskFunc: updateWith(models.Task{ID: 1, Title: "Title"}),
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodPatch, "/tasks?id=1", strings.NewReader(`{"title":null}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := httptest.NewRecorder()
+
+	h.PatchTask(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestPatchTask_UnsupportedContentType(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodPatch, "/tasks?id=1", strings.NewReader(`title=x`))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	h.PatchTask(w, req)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected status %d, got %d", http.StatusUnsupportedMediaType, w.Code)
+	}
+	if w.Header().Get("Accept-Patch") == "" {
+		t.Errorf("expected Accept-Patch header")
+	}
+}
+
+func TestPatchTask_JSONPatch(t *testing.T) {
+	mockSvc := &MockTaskService{
+		UpdateTaskFunc: updateWith(models.Task{ID: 1, Title: "Title", Description: "Desc"}),
+	}
+	h := NewHandlers(mockSvc)
+
+	body := `[{"op":"test","path":"/title","value":"Title"},{"op":"replace","path":"/title","value":"New"},{"op":"remove","path":"/description"}]`
+	req := httptest.NewRequest(http.MethodPatch, "/tasks?id=1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	w := httptest.NewRecorder()
+
+	h.PatchTask(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	var task models.Task
+	json.NewDecoder(w.Body).Decode(&task)
+	if task.Title != "New" || task.Description != "" {
+		t.Errorf("expected patched task, got %+v", task)
+	}
+}
+
+func TestPatchTask_JSONPatchTestFailed(t *testing.T) {
+	mockSvc := &MockTaskService{
+		UpdateTaskFunc: updateWith(models.Task{ID: 1, Title: "Title"}),
+	}
+	h := NewHandlers(mockSvc)
+
+	body := `[{"op":"test","path":"/title","value":"Other"},{"op":"replace","path":"/title","value":"New"}]`
+	req := httptest.NewRequest(http.MethodPatch, "/tasks?id=1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	w := httptest.NewRecorder()
+
+	h.PatchTask(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestPatchTask_JSONPatchInvalidPath(t *testing.T) {
+	mockSvc := &MockTaskService{
+		UpdateTaskFunc: updateWith(models.Task{ID: 1, Title: "Title"}),
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodPatch, "/tasks?id=1", strings.NewReader(`[{"op":"remove","path":"/nope"}]`))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	w := httptest.NewRecorder()
+
+	h.PatchTask(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetTask_ETagAndNotModified(t *testing.T) {
+	mockSvc := &MockTaskService{
+		GetTaskFunc: func(ctx context.Context, id int) (models.Task, error) {
+			return models.Task{ID: 1, Title: "Task 1", Version: 3}, nil
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?id=1", nil)
+	w := httptest.NewRecorder()
+	h.GetTask(w, req)
+
+	tag := w.Header().Get("ETag")
+	if tag != `"3"` {
+		t.Fatalf("expected ETag %q, got %q", `"3"`, tag)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/tasks?id=1", nil)
+	req.Header.Set("If-None-Match", tag)
+	w = httptest.NewRecorder()
+	h.GetTask(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", w.Body)
+	}
+}
+
+func TestUpdateTask_IfMatch(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"matching If-Match", "If-Match", `"2"`, http.StatusOK},
+		{"stale If-Match", "If-Match", `"1"`, http.StatusPreconditionFailed},
+		{"weak If-Match never matches", "If-Match", `W/"2"`, http.StatusPreconditionFailed},
+		{"wildcard If-Match", "If-Match", `*`, http.StatusOK},
+		{"If-None-Match wildcard", "If-None-Match", `*`, http.StatusPreconditionFailed},
+		{"If-None-Match other version", "If-None-Match", `"1"`, http.StatusOK},
+	}
+	for _, c := range cases {
+		mockSvc := &MockTaskService{
+			UpdateTaskFunc: updateWith(models.Task{ID: 1, Title: "Old", Version: 2}),
+		}
+		h := NewHandlers(mockSvc)
+
+		req := httptest.NewRequest(http.MethodPut, "/tasks?id=1", strings.NewReader(`{"title":"New"}`))
+		req.Header.Set(c.header, c.value)
+		w := httptest.NewRecorder()
+		h.UpdateTask(w, req)
+
+		if w.Code != c.want {
+			t.Errorf("%s: expected status %d, got %d", c.name, c.want, w.Code)
+		}
+	}
+}
+
+func TestDeleteTask_StaleIfMatch(t *testing.T) {
+	mockSvc := &MockTaskService{
+		DeleteTaskFunc: func(ctx context.Context, id int, precondition func(models.Task) error) error {
+			if precondition == nil {
+				t.Fatalf("expected a precondition")
+			}
+			return precondition(models.Task{ID: id, Title: "Task", Version: 5})
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodDelete, "/tasks?id=1", nil)
+	req.Header.Set("If-Match", `"4"`)
+	w := httptest.NewRecorder()
+	h.DeleteTask(w, req)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected status %d, got %d", http.StatusPreconditionFailed, w.Code)
+	}
+}
+
+func TestPatchTask_IllegalTransition(t *testing.T) {
+	mockSvc := &MockTaskService{
+		UpdateTaskFunc: func(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error) {
+			return models.Task{}, apperr.Wrap(&services.TransitionError{From: models.StatusCancelled, To: models.StatusDone}, apperr.Conflict, "invalid_transition")
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodPatch, "/tasks?id=1", strings.NewReader(`{"status":"done"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := httptest.NewRecorder()
+
+	h.PatchTask(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestCreateTask_UnknownStatus(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":"Task","status":"archived"}`))
+	w := httptest.NewRecorder()
+
+	h.CreateTask(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestCreateTask_PriorityOutOfRange(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":"Task","priority":9}`))
+	w := httptest.NewRecorder()
+
+	h.CreateTask(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetTasks_OverdueFilter(t *testing.T) {
+	var got models.TaskQuery
+	mockSvc := &MockTaskService{
+		GetTasksFunc: func(ctx context.Context, query models.TaskQuery) ([]models.Task, error) {
+			got = query
+			return []models.Task{}, nil
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?overdue=true", nil)
+	w := httptest.NewRecorder()
+	h.GetTasks(w, req)
+	if w.Code != http.StatusOK || !got.Overdue {
+		t.Fatalf("expected overdue query to be passed through, got %d %+v", w.Code, got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/tasks?overdue=maybe", nil)
+	w = httptest.NewRecorder()
+	h.GetTasks(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
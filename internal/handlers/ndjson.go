@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"task-manager/internal/apperr"
+	"task-manager/internal/models"
+	"task-manager/pkg/logger"
+)
+
+const (
+	NDJSONContentType = "application/x-ndjson"
+
+	// exportFlushEvery and exportFlushInterval bound how much of an export
+	// sits in the response buffer before it is pushed to the client.
+	exportFlushEvery    = 100
+	exportFlushInterval = time.Second
+
+	// maxImportLine is the longest NDJSON line accepted by ImportTasks.
+	maxImportLine = 1 << 20
+)
+
+// ExportTasks streams every task as newline-delimited JSON. Tasks are
+// written as the store yields them, so at most a chunk of the store is held
+// in memory at a time.
+func (h *Handlers) ExportTasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", NDJSONContentType)
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	pending := 0
+	lastFlush := time.Now()
+	err := h.taskSvc.ExportTasks(r.Context(), func(task models.Task) error {
+		if err := enc.Encode(task); err != nil {
+			return err
+		}
+		pending++
+		if pending >= exportFlushEvery || time.Since(lastFlush) >= exportFlushInterval {
+			pending, lastFlush = 0, time.Now()
+			return rc.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// The status line is already sent; all that is left is to stop.
+		if !errors.Is(err, r.Context().Err()) {
+			logger.ErrorContext(r.Context(), "task export failed", "error", err)
+		}
+		return
+	}
+	rc.Flush()
+}
+
+// importError describes an NDJSON line that could not be imported.
+type importError struct {
+	Line  int    `json:"line"`
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+type importResult struct {
+	Imported int           `json:"imported"`
+	Failed   int           `json:"failed"`
+	Errors   []importError `json:"errors"`
+}
+
+// ImportTasks creates a task from every line of an NDJSON body. A bad line
+// does not stop the import; it is reported with its line number. Lines are
+// decoded as strictly as a request body, except that server-owned members
+// are always dropped rather than subject to the server fields policy: an
+// export carries them on every line, and tasks get new ids and timestamps,
+// so an export can be loaded into another instance.
+func (h *Handlers) ImportTasks(w http.ResponseWriter, r *http.Request) {
+	result := importResult{Errors: []importError{}}
+	fail := func(line int, err error) {
+		p := problemOf(err, "")
+		if p.Detail == "" {
+			logger.ErrorContext(r.Context(), "task import failed", "line", line, "error", err)
+			p.Detail = p.Title
+		}
+		result.Failed++
+		result.Errors = append(result.Errors, importError{Line: line, Code: p.Code, Error: p.Detail})
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		if err := r.Context().Err(); err != nil {
+			return
+		}
+
+		task, err := decodeImportLine(data)
+		if err != nil {
+			fail(line, err)
+			continue
+		}
+		if err := h.validator.Validate(&task); err != nil {
+			fail(line, apperr.Invalid(err))
+			continue
+		}
+		if _, err := h.taskSvc.CreateTask(r.Context(), task); err != nil {
+			fail(line, err)
+			continue
+		}
+		result.Imported++
+	}
+	if err := scanner.Err(); err != nil {
+		// The rest of the body cannot be split into lines any more.
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = apperr.New(apperr.BadRequest, "line_too_long", fmt.Sprintf("line exceeds %d bytes, import stopped", maxImportLine))
+		}
+		fail(line+1, err)
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// decodeImportLine reads the task on one NDJSON line, refusing members a
+// task does not have and anything after the task, and clears its
+// server-owned members.
+func decodeImportLine(data []byte) (models.Task, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var task models.Task
+	if err := dec.Decode(&task); err != nil {
+		return models.Task{}, decodeError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return models.Task{}, apperr.New(apperr.BadRequest, "malformed_body", "line must hold a single JSON value")
+	}
+	clearServerFields(&task)
+	return task, nil
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"task-manager/internal/models"
+)
+
+func TestExportTasks(t *testing.T) {
+	mockSvc := &MockTaskService{
+		ExportTasksFunc: func(ctx context.Context, fn func(models.Task) error) error {
+			for i := 1; i <= 250; i++ {
+				if err := fn(models.Task{ID: i, Title: "Task"}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/export", nil)
+	w := httptest.NewRecorder()
+	h.ExportTasks(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != NDJSONContentType {
+		t.Errorf("expected content type %q, got %q", NDJSONContentType, ct)
+	}
+	if !w.Flushed {
+		t.Errorf("expected the export to be flushed")
+	}
+	scanner := bufio.NewScanner(w.Body)
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		var task models.Task
+		if err := json.Unmarshal(scanner.Bytes(), &task); err != nil || task.ID != lines {
+			t.Fatalf("line %d: expected task %d, got %v (%v)", lines, lines, task, err)
+		}
+	}
+	if lines != 250 {
+		t.Errorf("expected 250 lines, got %d", lines)
+	}
+}
+
+func TestExportTasks_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sent := 0
+	mockSvc := &MockTaskService{
+		ExportTasksFunc: func(ctx context.Context, fn func(models.Task) error) error {
+			for i := 1; i <= 1000; i++ {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if i == 10 {
+					cancel()
+				}
+				sent++
+				if err := fn(models.Task{ID: i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/export", nil).WithContext(ctx)
+	h.ExportTasks(httptest.NewRecorder(), req)
+
+	if sent != 10 {
+		t.Errorf("expected export to stop after cancellation, sent %d tasks", sent)
+	}
+}
+
+func TestImportTasks(t *testing.T) {
+	var created []string
+	mockSvc := &MockTaskService{
+		CreateTaskFunc: func(ctx context.Context, task models.Task) (models.Task, error) {
+			if task.ID != 0 || task.Version != 0 {
+				t.Errorf("expected server fields to be dropped, got %+v", task)
+			}
+			created = append(created, task.Title)
+			task.ID = len(created)
+			return task, nil
+		},
+	}
+	// Exported lines carry server fields, so they are dropped even when a
+	// request body would be rejected for them.
+	h := NewHandlers(mockSvc, WithServerFields(RejectServerFields))
+
+	body := strings.Join([]string{
+		`{"id":7,"version":2,"title":"First"}`,
+		`{"title":`,
+		``,
+		`{"title":""}`,
+		`{"title":"Second","priority":2}`,
+		`{"title":"imp","bogus":1}`,
+		`{"title":"a"} {"title":"b"}`,
+	}, "\n")
+	req := httptest.NewRequest(http.MethodPost, "/tasks/import", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ImportTasks(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var result importResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("cannot decode response body: %v", err)
+	}
+	if result.Imported != 2 || result.Failed != 4 {
+		t.Fatalf("expected 2 imported and 4 failed, got %+v", result)
+	}
+	if result.Errors[0].Line != 2 || result.Errors[1].Line != 4 {
+		t.Errorf("expected errors on lines 2 and 4, got %+v", result.Errors)
+	}
+	if result.Errors[2].Line != 6 || result.Errors[2].Code != "unknown_field" || result.Errors[3].Code != "malformed_body" {
+		t.Errorf("expected an unknown member on line 6 and two values on line 7, got %+v", result.Errors[2:])
+	}
+	if len(created) != 2 || created[1] != "Second" {
+		t.Errorf("expected two tasks to be created, got %v", created)
+	}
+}
+
+func TestImportTasks_LineTooLong(t *testing.T) {
+	mockSvc := &MockTaskService{
+		CreateTaskFunc: func(ctx context.Context, task models.Task) (models.Task, error) {
+			return task, nil
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	body := `{"title":"Ok"}` + "\n" + `{"title":"` + strings.Repeat("x", maxImportLine) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/tasks/import", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ImportTasks(w, req)
+
+	var result importResult
+	json.NewDecoder(w.Body).Decode(&result)
+	if result.Imported != 1 || result.Failed != 1 || result.Errors[0].Line != 2 {
+		t.Errorf("expected the long line to be reported, got %+v", result)
+	}
+}
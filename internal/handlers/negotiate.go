@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"task-manager/internal/apperr"
+	"task-manager/internal/models"
+)
+
+// format is a response media type the task endpoints can render.
+type format string
+
+const (
+	formatJSON format = "application/json"
+	formatCSV  format = "text/csv"
+	formatYAML format = "application/yaml"
+)
+
+// formats are listed in order of preference for ties in the Accept header.
+var formats = []format{formatJSON, formatCSV, formatYAML}
+
+// formatAliases are other names clients use for the supported types.
+var formatAliases = map[string]format{
+	"application/x-yaml": formatYAML,
+	"text/yaml":          formatYAML,
+	"text/x-yaml":        formatYAML,
+}
+
+const supportedFormats = "application/json, text/csv, application/yaml"
+
+// negotiate picks the response format from an Accept header (RFC 9110,
+// section 12.5.1). Each format gets the quality of the most specific range
+// matching it; the best non-zero quality wins. It reports false when the
+// client accepts none of the formats.
+func negotiate(accept string) (format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return formatJSON, true
+	}
+
+	type acceptRange struct {
+		typ, subtype string
+		q            float64
+	}
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if alias, ok := formatAliases[mediaType]; ok {
+			mediaType = string(alias)
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		ranges = append(ranges, acceptRange{typ, subtype, q})
+	}
+
+	best, bestQ := format(""), 0.0
+	for _, f := range formats {
+		typ, subtype, _ := strings.Cut(string(f), "/")
+		q, specificity := 0.0, -1
+		for _, ar := range ranges {
+			s := -1
+			switch {
+			case ar.typ == typ && ar.subtype == subtype:
+				s = 2
+			case ar.typ == typ && ar.subtype == "*":
+				s = 1
+			case ar.typ == "*" && ar.subtype == "*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = ar.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = f, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// acceptable negotiates the response format and answers 406 Not Acceptable
+// when there is none.
+func (h *Handlers) acceptable(w http.ResponseWriter, r *http.Request) (format, bool) {
+	w.Header().Add("Vary", "Accept")
+	f, ok := negotiate(r.Header.Get("Accept"))
+	if !ok {
+		h.fail(w, r, apperr.New(apperr.NotAcceptable, "not_acceptable", "supported response types: "+supportedFormats))
+	}
+	return f, ok
+}
+
+// render writes body in format f. JSON and YAML encode the version's
+// document for body and meta; CSV has one row per task, restricted to fields
+// when given.
+func (h *Handlers) render(w http.ResponseWriter, r *http.Request, status int, f format, body any, meta Meta, tasks []models.Task, fields []string) {
+	var data []byte
+	var err error
+	switch f {
+	case formatCSV:
+		data, err = encodeCSV(tasks, fields)
+	case formatYAML:
+		data, err = encodeYAML(h.serializer.Body(body, meta))
+	default:
+		var buf bytes.Buffer
+		err = json.NewEncoder(&buf).Encode(h.serializer.Body(body, meta))
+		data = buf.Bytes()
+	}
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	contentType := string(f)
+	if f != formatJSON {
+		contentType += "; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// renderTask writes a single task in format f.
+func (h *Handlers) renderTask(w http.ResponseWriter, r *http.Request, status int, f format, task models.Task) {
+	h.render(w, r, status, f, task, Meta{}, []models.Task{task}, nil)
+}
+
+// writeJSON writes data as the version's JSON document.
+func (h *Handlers) writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", string(formatJSON))
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(h.serializer.Body(data, Meta{}))
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"task-manager/internal/apperr"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   format
+		ok     bool
+	}{
+		{"", formatJSON, true},
+		{"*/*", formatJSON, true},
+		{"text/csv", formatCSV, true},
+		{"application/x-yaml", formatYAML, true},
+		{"text/*", formatCSV, true},
+		{"application/yaml;q=0.9, text/csv;q=0.5", formatYAML, true},
+		{"application/json;q=0, */*;q=0.1", formatCSV, true},
+		{"text/html, application/xml", "", false},
+		{"application/json;q=0", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := negotiate(tt.accept)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("negotiate(%q) = %q, %v; want %q, %v", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGetTasks_CSV(t *testing.T) {
+	due := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	mockSvc := &MockTaskService{
+		GetTasksFunc: func(ctx context.Context, query models.TaskQuery) ([]models.Task, error) {
+			return []models.Task{
+				{ID: 1, Title: `Quote "this", please`, Description: "two\nlines", Status: models.StatusTodo, DueAt: &due},
+				{ID: 2, Title: "Plain"},
+				{ID: 3, Title: "=HYPERLINK(\"x\")", Description: "-1 day"},
+			}, nil
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?fields=id,title,description,due_at", nil)
+	req.Header.Set("Accept", "text/csv")
+	w := httptest.NewRecorder()
+	h.GetTasks(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("expected text/csv, got %q", ct)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("cannot parse csv: %v", err)
+	}
+	want := [][]string{
+		{"id", "title", "description", "due_at"},
+		{"1", `Quote "this", please`, "two\nlines", "2024-05-01T09:00:00Z"},
+		{"2", "Plain", "", ""},
+		{"3", `'=HYPERLINK("x")`, "'-1 day", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %v, got %v", want, records)
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d: expected %q, got %q", i, want[i], records[i])
+		}
+	}
+}
+
+func TestGetTask_YAML(t *testing.T) {
+	mockSvc := &MockTaskService{
+		GetTaskFunc: func(ctx context.Context, id int) (models.Task, error) {
+			return models.Task{ID: id, Title: "Say \"hi\"", Status: models.StatusTodo, Version: 1}, nil
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?id=3", nil)
+	req.Header.Set("Accept", "application/yaml")
+	w := httptest.NewRecorder()
+	h.GetTask(w, req)
+
+	want := `id: 3
+title: "Say \"hi\""
+description: ""
+status: "todo"
+priority: 0
+version: 1
+`
+	if w.Body.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, w.Body.String())
+	}
+}
+
+func TestEncodeYAML_Nested(t *testing.T) {
+	got, err := encodeYAML(map[string]any{
+		"tasks": []any{map[string]any{"id": 1, "tags": []string{"a"}}, []int{}},
+		"next":  nil,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `next: null
+tasks:
+  - id: 1
+    tags:
+      - "a"
+  - []
+`
+	if string(got) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestGetTasks_NotAcceptable(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	req.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+	h.GetTasks(w, req)
+
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected status %d, got %d", http.StatusNotAcceptable, w.Code)
+	}
+}
+
+func TestCreateTask_CSV(t *testing.T) {
+	var created []models.Task
+	mockSvc := &MockTaskService{
+		BatchFunc: func(ctx context.Context, items []services.BatchItem, atomic bool) ([]services.BatchResult, error) {
+			if !atomic {
+				t.Errorf("expected the rows to be created atomically")
+			}
+			results := make([]services.BatchResult, len(items))
+			for i, item := range items {
+				item.Task.ID = i + 1
+				created = append(created, item.Task)
+				results[i].Task = item.Task
+			}
+			return results, nil
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	body := "title,priority,due_at\nFirst,3,2030-01-01T00:00:00Z\n\"Second, with comma\",,\n'=SUM(A1),,\n"
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	h.CreateTask(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	if len(created) != 3 || created[0].Priority != 3 || created[0].DueAt == nil || created[1].Title != "Second, with comma" || created[2].Title != "=SUM(A1)" {
+		t.Errorf("unexpected tasks %+v", created)
+	}
+}
+
+func TestCreateTask_CSVStorageFailure(t *testing.T) {
+	mockSvc := &MockTaskService{
+		BatchFunc: func(ctx context.Context, items []services.BatchItem, atomic bool) ([]services.BatchResult, error) {
+			return nil, &services.BatchError{Index: 1, Err: apperr.New(apperr.Conflict, "conflict", "row clashes")}
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader("title\nFirst\nSecond\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	h.CreateTask(w, req)
+
+	if p := decodeProblem(t, w); w.Code != http.StatusConflict || p.Code != "conflict" {
+		t.Fatalf("expected the batch failure, got %d %+v", w.Code, p)
+	}
+}
+
+func TestCreateTask_CSVInvalidRows(t *testing.T) {
+	mockSvc := &MockTaskService{
+		BatchFunc: func(ctx context.Context, items []services.BatchItem, atomic bool) ([]services.BatchResult, error) {
+			t.Fatalf("no task should be created")
+			return nil, nil
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	body := "title,priority\nOk,1\n,2\nBad,high\n"
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	h.CreateTask(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if msg := w.Body.String(); !strings.Contains(msg, "line 3") || !strings.Contains(msg, "line 4") {
+		t.Errorf("expected both bad lines to be reported, got %q", msg)
+	}
+}
+
+func TestCreateTask_UnsupportedMediaType(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader("<task/>"))
+	req.Header.Set("Content-Type", "application/xml")
+	w := httptest.NewRecorder()
+	h.CreateTask(w, req)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected status %d, got %d", http.StatusUnsupportedMediaType, w.Code)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"task-manager/internal/apperr"
+	"task-manager/internal/models"
+)
+
+var errPreconditionFailed = apperr.New(apperr.Precondition, "precondition_failed", "precondition failed")
+
+// etag is a strong entity tag derived from the task version.
+func etag(task models.Task) string {
+	return `"` + strconv.Itoa(task.Version) + `"`
+}
+
+// matchETag reports whether tag is listed in an If-Match or If-None-Match
+// header value. "*" matches any existing task. Weak tags only match when
+// weak comparison is allowed (RFC 9110, section 8.8.3.2).
+func matchETag(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = candidate[2:]
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch checks a task against an If-Match value.
+func ifMatch(header string) func(models.Task) error {
+	return func(task models.Task) error {
+		if !matchETag(header, etag(task), false) {
+			return errPreconditionFailed
+		}
+		return nil
+	}
+}
+
+// writePreconditions evaluates If-Match and If-None-Match for a request that
+// modifies task, returning errPreconditionFailed when the client's view is
+// stale.
+func writePreconditions(r *http.Request) func(models.Task) error {
+	ifMatch := r.Header.Get("If-Match")
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifMatch == "" && ifNoneMatch == "" {
+		return nil
+	}
+	return func(task models.Task) error {
+		if ifMatch != "" && !matchETag(ifMatch, etag(task), false) {
+			return errPreconditionFailed
+		}
+		if ifNoneMatch != "" && matchETag(ifNoneMatch, etag(task), true) {
+			return errPreconditionFailed
+		}
+		return nil
+	}
+}
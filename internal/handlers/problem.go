@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-manager/internal/apperr"
+	"task-manager/pkg/logger"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object. Code and Errors are
+// extension members: a stable identifier of the problem and the invalid
+// fields, if any.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code"`
+	Errors   []apperr.FieldError `json:"errors,omitempty"`
+}
+
+// kindStatus maps an error kind to its HTTP status code.
+func kindStatus(kind apperr.Kind) int {
+	switch kind {
+	case apperr.NotFound:
+		return http.StatusNotFound
+	case apperr.Validation, apperr.BadRequest:
+		return http.StatusBadRequest
+	case apperr.Conflict:
+		return http.StatusConflict
+	case apperr.Precondition:
+		return http.StatusPreconditionFailed
+	case apperr.RateLimited:
+		return http.StatusTooManyRequests
+	case apperr.Unsupported:
+		return http.StatusUnsupportedMediaType
+	case apperr.NotAcceptable:
+		return http.StatusNotAcceptable
+	case apperr.TooLarge:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorStatus maps an error from a task change to an HTTP status code.
+func errorStatus(err error) int {
+	return kindStatus(apperr.From(err).Kind)
+}
+
+// problemOf describes err for clients. Internal errors are reduced to their
+// status, so nothing about the cause reaches the response.
+func problemOf(err error, instance string) Problem {
+	e := apperr.From(err)
+	status := kindStatus(e.Kind)
+	p := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: instance,
+		Code:     e.Code,
+	}
+	if e.Kind != apperr.Internal {
+		p.Detail = e.Message
+		p.Errors = e.Fields
+	}
+	return p
+}
+
+// fail writes err as an error response in the handlers' API version.
+// Internal errors are logged with their cause.
+func (h *Handlers) fail(w http.ResponseWriter, r *http.Request, err error) {
+	e := apperr.From(err)
+	if e.Kind == apperr.Internal {
+		logger.ErrorContext(r.Context(), "request failed", "path", r.URL.Path, "error", err)
+	}
+	if e.Kind == apperr.RateLimited && e.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(e.RetryAfter.Round(time.Second)/time.Second)))
+	}
+	h.serializer.Error(w, problemOf(e, h.basePath+r.URL.Path))
+}
+
+// writeProblem writes p as an application/problem+json document.
+func writeProblem(w http.ResponseWriter, p Problem) {
+	writeProblemDocument(w, p.Status, p)
+}
+
+// writeProblemDocument writes doc, a problem with any extension members, as
+// an application/problem+json response with the given status.
+func writeProblemDocument(w http.ResponseWriter, status int, doc any) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(doc)
+}
+
+// decodeError describes a request body that could not be decoded, without
+// the decoder's wording.
+func decodeError(err error) *apperr.Error {
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	var parse *time.ParseError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return apperr.New(apperr.TooLarge, "body_too_large", fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+	case errors.Is(err, io.EOF):
+		return apperr.New(apperr.BadRequest, "malformed_body", "request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return apperr.New(apperr.BadRequest, "malformed_body", "request body is truncated")
+	case errors.As(err, &syntax):
+		return apperr.New(apperr.BadRequest, "malformed_body", fmt.Sprintf("request body is not valid JSON (at byte %d)", syntax.Offset))
+	case errors.As(err, &typ):
+		detail := fmt.Sprintf("must be %s", jsonType(typ.Type))
+		e := apperr.New(apperr.BadRequest, "malformed_body", fmt.Sprintf("%s %s", typ.Field, detail))
+		if typ.Field != "" {
+			// The decoder names nested fields with dots.
+			pointer := "/" + strings.ReplaceAll(typ.Field, ".", "/")
+			e.Fields = []apperr.FieldError{{Pointer: pointer, Detail: detail}}
+		} else {
+			e.Message = "request body " + detail
+		}
+		return e
+	case errors.As(err, &parse):
+		return apperr.New(apperr.BadRequest, "malformed_body", fmt.Sprintf("invalid time %q, use RFC 3339", parse.Value))
+	default:
+		if name, ok := unknownField(err); ok {
+			return apperr.New(apperr.BadRequest, "unknown_field", fmt.Sprintf("unknown field %q", name))
+		}
+		return apperr.New(apperr.BadRequest, "malformed_body", "request body could not be decoded")
+	}
+}
+
+// jsonType names the JSON type a Go type is decoded from.
+func jsonType(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+)
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Fatalf("expected %s, got %q", ProblemContentType, ct)
+	}
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("cannot decode problem: %v", err)
+	}
+	if p.Status != w.Code {
+		t.Errorf("expected problem status %d to match the response, got %d", w.Code, p.Status)
+	}
+	return p
+}
+
+func TestProblem_MalformedJSON(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":`))
+	w := httptest.NewRecorder()
+	h.CreateTask(w, req)
+
+	p := decodeProblem(t, w)
+	if w.Code != http.StatusBadRequest || p.Code != "malformed_body" {
+		t.Fatalf("expected 400 malformed_body, got %d %+v", w.Code, p)
+	}
+	if strings.Contains(p.Detail, "unexpected EOF") {
+		t.Errorf("expected the decoder's wording to stay out of the detail, got %q", p.Detail)
+	}
+}
+
+func TestProblem_WrongFieldType(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":"Task","priority":"high"}`))
+	w := httptest.NewRecorder()
+	h.CreateTask(w, req)
+
+	p := decodeProblem(t, w)
+	if len(p.Errors) != 1 || p.Errors[0].Pointer != "/priority" || p.Errors[0].Detail != "must be an integer" {
+		t.Fatalf("expected a priority field error, got %+v", p)
+	}
+}
+
+func TestProblem_ValidationFields(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"description":"no title"}`))
+	w := httptest.NewRecorder()
+	h.CreateTask(w, req)
+
+	p := decodeProblem(t, w)
+	if w.Code != http.StatusBadRequest || p.Code != "validation_failed" {
+		t.Fatalf("expected 400 validation_failed, got %d %+v", w.Code, p)
+	}
+	if len(p.Errors) != 1 || p.Errors[0].Pointer != "/title" {
+		t.Fatalf("expected a title field error, got %+v", p.Errors)
+	}
+}
+
+func TestProblem_AllViolations(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	body := `{"title":"` + strings.Repeat("x", 201) + `","description":"bell\u0007","status":"archived","priority":-1}`
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.CreateTask(w, req)
+
+	p := decodeProblem(t, w)
+	var pointers []string
+	for _, e := range p.Errors {
+		pointers = append(pointers, e.Pointer)
+	}
+	want := []string{"/title", "/description", "/status", "/priority"}
+	if strings.Join(pointers, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected violations at %v, got %+v", want, p.Errors)
+	}
+}
+
+func TestProblem_NotFound(t *testing.T) {
+	h := NewHandlers(&MockTaskService{
+		GetTaskFunc: func(ctx context.Context, id int) (models.Task, error) {
+			return models.Task{}, services.ErrTaskNotFound
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/7", nil)
+	req.SetPathValue("id", "7")
+	w := httptest.NewRecorder()
+	h.GetTask(w, req)
+
+	p := decodeProblem(t, w)
+	if w.Code != http.StatusNotFound || p.Code != "task_not_found" || p.Instance != "/tasks/7" {
+		t.Fatalf("expected a task_not_found problem for /tasks/7, got %d %+v", w.Code, p)
+	}
+}
+
+func TestProblem_InternalErrorIsHidden(t *testing.T) {
+	h := NewHandlers(&MockTaskService{
+		GetTasksFunc: func(ctx context.Context, query models.TaskQuery) ([]models.Task, error) {
+			return nil, errors.New("open /var/lib/tasks/wal: permission denied")
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	w := httptest.NewRecorder()
+	h.GetTasks(w, req)
+
+	body := w.Body.String()
+	p := decodeProblem(t, w)
+	if w.Code != http.StatusInternalServerError || p.Code != "internal" {
+		t.Fatalf("expected 500 internal, got %d %+v", w.Code, p)
+	}
+	if strings.Contains(body, "permission denied") {
+		t.Errorf("expected the cause to stay out of the response, got %s", body)
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"task-manager/internal/models"
+)
+
+// filterAliases are shorthands for range filters on timestamps.
+var filterAliases = map[string]struct {
+	field string
+	op    models.Op
+}{
+	"created_after":  {"created_at", models.OpGt},
+	"created_before": {"created_at", models.OpLt},
+	"updated_after":  {"updated_at", models.OpGt},
+	"updated_before": {"updated_at", models.OpLt},
+	"due_after":      {"due_at", models.OpGt},
+	"due_before":     {"due_at", models.OpLt},
+}
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 1000
+)
+
+// listParams holds the parsed query string of a task listing.
+type listParams struct {
+	query  models.TaskQuery
+	fields []string
+	// limit is set when the client asked for a page; after is the cursor
+	// token of the previous page.
+	limit int
+	after string
+}
+
+// paged reports whether the listing is split into pages.
+func (p *listParams) paged() bool {
+	return p.limit > 0
+}
+
+// taskPage is the response envelope of a paged listing.
+type taskPage struct {
+	Tasks any    `json:"tasks"`
+	Next  string `json:"next,omitempty"`
+}
+
+// nextPageURL repeats the request with the after parameter replaced by token.
+// basePath is the prefix the router stripped from u.
+func nextPageURL(basePath string, u *url.URL, token string) string {
+	pairs := []string{}
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if key, _, _ := strings.Cut(pair, "="); pair != "" && key != "after" {
+			pairs = append(pairs, pair)
+		}
+	}
+	pairs = append(pairs, "after="+url.QueryEscape(token))
+	return basePath + u.Path + "?" + strings.Join(pairs, "&")
+}
+
+// parseListParams reads filters, sort, fields and paging from a raw query
+// string.
+// Filters are written as field, operator and value, e.g. "priority>=3" or
+// "title~=report"; the "=" of a pair is part of the operator, so the query
+// is split by hand rather than with url.ParseQuery.
+func parseListParams(rawQuery string) (listParams, error) {
+	var p listParams
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		rawKey, rawValue, hasValue := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return p, fmt.Errorf("invalid query parameter %q", rawKey)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return p, fmt.Errorf("invalid value for %q", key)
+		}
+
+		op := models.OpEq
+		if hasValue {
+			if i := len(key) - 1; i > 0 && strings.ContainsRune("!<>~", rune(key[i])) {
+				key, op = key[:i], models.Op(key[i:]+"=")
+			}
+		} else if i := strings.IndexAny(key, "<>"); i > 0 {
+			key, op, value = key[:i], models.Op(key[i:i+1]), key[i+1:]
+		} else {
+			return p, fmt.Errorf("query parameter %q has no value", key)
+		}
+
+		if err := p.set(key, op, value); err != nil {
+			return p, err
+		}
+	}
+	if p.after != "" && p.limit == 0 {
+		p.limit = defaultListLimit
+	}
+	return p, nil
+}
+
+func (p *listParams) set(key string, op models.Op, value string) error {
+	switch key {
+	case "overdue", "sort", "fields", "limit", "after":
+		if op != models.OpEq {
+			return fmt.Errorf("operator %s is not supported for %s", op, key)
+		}
+	}
+
+	switch key {
+	case "overdue":
+		overdue, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid overdue value %q", value)
+		}
+		p.query.Overdue = overdue
+	case "limit":
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			return fmt.Errorf("limit must be between 1 and %d, got %q", maxListLimit, value)
+		}
+		p.limit = limit
+	case "after":
+		p.after = value
+	case "sort":
+		keys, err := models.ParseSort(value)
+		if err != nil {
+			return err
+		}
+		p.query.Sort = keys
+	case "fields":
+		p.fields = p.fields[:0]
+		for _, field := range strings.Split(value, ",") {
+			if !slices.Contains(models.TaskFields, field) {
+				return fmt.Errorf("unknown field %q", field)
+			}
+			p.fields = append(p.fields, field)
+		}
+	default:
+		if alias, ok := filterAliases[key]; ok {
+			if op != models.OpEq {
+				return fmt.Errorf("operator %s is not supported for %s", op, key)
+			}
+			key, op = alias.field, alias.op
+		}
+		filter, err := models.NewFilter(key, op, value)
+		if err != nil {
+			return err
+		}
+		p.query.Filters = append(p.query.Filters, filter)
+	}
+	return nil
+}
+
+// selectFields reduces each task to the requested JSON members.
+func selectFields(tasks []models.Task, fields []string) ([]map[string]json.RawMessage, error) {
+	selected := make([]map[string]json.RawMessage, 0, len(tasks))
+	for _, task := range tasks {
+		data, err := json.Marshal(task)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+		item := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				item[field] = value
+			}
+		}
+		selected = append(selected, item)
+	}
+	return selected, nil
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"task-manager/internal/models"
+)
+
+func TestParseListParams(t *testing.T) {
+	tests := []struct {
+		query   string
+		filters []string
+		sort    []models.SortKey
+		fields  []string
+	}{
+		{query: "status=todo,in_progress", filters: []string{"status=todo,in_progress"}},
+		{query: "priority>=3&priority<5", filters: []string{"priority>=3", "priority<5"}},
+		{query: "priority%3E2", filters: []string{"priority>2"}},
+		{query: "title~=report&status!=done", filters: []string{"title~=report", "status!=done"}},
+		{query: "created_after=2024-01-02", filters: []string{"created_at>2024-01-02T00:00:00Z"}},
+		{query: "sort=-priority,created_at", sort: []models.SortKey{{Field: "priority", Desc: true}, {Field: "created_at"}}},
+		{query: "fields=id,title", fields: []string{"id", "title"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			p, err := parseListParams(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(p.query.Filters) != len(tt.filters) {
+				t.Fatalf("expected %d filters, got %v", len(tt.filters), p.query.Filters)
+			}
+			for i, f := range p.query.Filters {
+				if f.String() != tt.filters[i] {
+					t.Errorf("expected filter %q, got %q", tt.filters[i], f.String())
+				}
+			}
+			if len(p.query.Sort) != len(tt.sort) {
+				t.Fatalf("expected sort %v, got %v", tt.sort, p.query.Sort)
+			}
+			for i, key := range p.query.Sort {
+				if key != tt.sort[i] {
+					t.Errorf("expected sort key %v, got %v", tt.sort[i], key)
+				}
+			}
+			if len(p.fields) != len(tt.fields) {
+				t.Errorf("expected fields %v, got %v", tt.fields, p.fields)
+			}
+		})
+	}
+}
+
+func TestParseListParams_Invalid(t *testing.T) {
+	for _, query := range []string{
+		"colour=red",
+		"priority>=high",
+		"status=archived",
+		"status>todo",
+		"priority~=3",
+		"created_after=yesterday",
+		"sort=-colour",
+		"fields=id,colour",
+		"sort>=title",
+		"title",
+	} {
+		if _, err := parseListParams(query); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestGetTasks_InvalidFilter(t *testing.T) {
+	h := NewHandlers(&MockTaskService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?priority>=high", nil)
+	w := httptest.NewRecorder()
+	h.GetTasks(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetTasks_Fields(t *testing.T) {
+	mockSvc := &MockTaskService{
+		GetTasksFunc: func(ctx context.Context, query models.TaskQuery) ([]models.Task, error) {
+			return []models.Task{{ID: 1, Title: "Task 1", Description: "Desc", Priority: 2}}, nil
+		},
+	}
+	h := NewHandlers(mockSvc)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks?fields=id,title", nil)
+	w := httptest.NewRecorder()
+	h.GetTasks(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var got []map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || len(got[0]) != 2 || got[0]["title"] != "Task 1" {
+		t.Errorf("expected only id and title, got %v", got)
+	}
+}
+
+// listService pages through a fixed set of tasks the way the repository does.
+func listService(tasks []models.Task) *MockTaskService {
+	return &MockTaskService{
+		GetTasksFunc: func(ctx context.Context, query models.TaskQuery) ([]models.Task, error) {
+			var page []models.Task
+			for _, task := range tasks {
+				if query.Match(&task, time.Now()) {
+					page = append(page, task)
+				}
+			}
+			query.SortTasks(page)
+			if query.Limit > 0 && len(page) > query.Limit {
+				page = page[:query.Limit]
+			}
+			return page, nil
+		},
+	}
+}
+
+type pageResponse struct {
+	Tasks []models.Task `json:"tasks"`
+	Next  string        `json:"next"`
+}
+
+func getPage(t *testing.T, h *Handlers, target string) (pageResponse, *httptest.ResponseRecorder) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	w := httptest.NewRecorder()
+	h.GetTasks(w, req)
+	var page pageResponse
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+			t.Fatalf("cannot decode response body: %v", err)
+		}
+	}
+	return page, w
+}
+
+func TestGetTasks_Pagination(t *testing.T) {
+	var tasks []models.Task
+	for i := 1; i <= 5; i++ {
+		tasks = append(tasks, models.Task{ID: i, Title: "Task", Priority: i % 3})
+	}
+	h := NewHandlers(listService(tasks), WithCursorKey([]byte("secret")))
+
+	var ids []int
+	target := "/tasks?sort=-priority&limit=2"
+	for pages := 0; target != ""; pages++ {
+		if pages > 5 {
+			t.Fatalf("pagination did not terminate")
+		}
+		page, w := getPage(t, h, target)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+		}
+		if page.Next != "" && w.Header().Get("Link") != "<"+page.Next+`>; rel="next"` {
+			t.Errorf("expected Link header for %q, got %q", page.Next, w.Header().Get("Link"))
+		}
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+		target = page.Next
+	}
+	want := []int{2, 5, 1, 4, 3}
+	if len(ids) != len(want) {
+		t.Fatalf("expected %v, got %v", want, ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, ids)
+		}
+	}
+}
+
+func TestGetTasks_InvalidCursor(t *testing.T) {
+	var tasks []models.Task
+	for i := 1; i <= 3; i++ {
+		tasks = append(tasks, models.Task{ID: i, Title: "Task"})
+	}
+	h := NewHandlers(listService(tasks), WithCursorKey([]byte("secret")))
+
+	page, _ := getPage(t, h, "/tasks?limit=1")
+	if page.Next == "" {
+		t.Fatalf("expected a next link")
+	}
+	u, _ := url.Parse(page.Next)
+	token := u.Query().Get("after")
+
+	payload, signature, _ := strings.Cut(token, ".")
+	tampered := payload[:len(payload)-1] + "A." + signature
+	if payload[len(payload)-1] == 'A' {
+		tampered = payload[:len(payload)-1] + "B." + signature
+	}
+	for name, target := range map[string]string{
+		"tampered":   "/tasks?limit=1&after=" + url.QueryEscape(tampered),
+		"other key":  "/tasks?limit=1&after=" + url.QueryEscape(token),
+		"other sort": "/tasks?limit=1&sort=title&after=" + url.QueryEscape(token),
+		"garbage":    "/tasks?after=garbage",
+	} {
+		handler := h
+		if name == "other key" {
+			handler = NewHandlers(listService(tasks), WithCursorKey([]byte("another")))
+		}
+		if _, w := getPage(t, handler, target); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", name, http.StatusBadRequest, w.Code)
+		}
+	}
+}
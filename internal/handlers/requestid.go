@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"net/http"
+	"strings"
+
+	"task-manager/pkg/logger"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds a client-supplied request ID.
+const maxRequestIDLength = 128
+
+// RequestID gives every request an ID: the client's X-Request-ID when it is
+// usable, a random one otherwise. The ID is echoed in the response and added,
+// with the method, to the logger in the request context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := logger.With(r.Context(), "request_id", id, "method", r.Method)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID accepts short IDs of printable ASCII, so a client cannot
+// forge log lines or bloat them through the header.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	return strings.IndexFunc(id, func(c rune) bool { return c <= ' ' || c > '~' }) < 0
+}
+
+// WithRoute adds the route mux matches, such as /v1/tasks/{id}, to the
+// logger in the request context.
+func (h *Handlers) WithRoute(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			// Patterns may start with a method, which is logged apart.
+			if _, path, ok := strings.Cut(pattern, " "); ok {
+				pattern = path
+			}
+			r = r.WithContext(logger.With(r.Context(), "route", h.basePath+pattern))
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"task-manager/pkg/logger"
+)
+
+func TestRequestID(t *testing.T) {
+	h := NewHandlers(&MockTaskService{}, WithBasePath("/v1"))
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "task deleted", "task_id", r.PathValue("id"))
+	})
+	handler := RequestID(h.WithRoute(mux))
+
+	tests := []struct {
+		name, header string
+		keep         bool
+	}{
+		{"accepted", "req-42", true},
+		{"generated", "", false},
+		{"unprintable", "bad\nid", false},
+		{"too long", strings.Repeat("x", maxRequestIDLength+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			req := httptest.NewRequest(http.MethodDelete, "/tasks/5", nil)
+			req = req.WithContext(logger.NewContext(req.Context(), slog.New(slog.NewJSONHandler(&buf, nil))))
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if tt.keep && id != tt.header || !tt.keep && (id == "" || id == tt.header) {
+				t.Fatalf("unexpected request ID %q for header %q", id, tt.header)
+			}
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("expected one JSON record, got %q", buf.String())
+			}
+			if record["request_id"] != id || record["method"] != "DELETE" || record["route"] != "/v1/tasks/{id}" || record["task_id"] != "5" {
+				t.Errorf("unexpected record %v", record)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-manager/internal/apperr"
+)
+
+// QueryIDDeprecated is when the ?id= forms of the task endpoints were
+// superseded by /tasks/{id}.
+var QueryIDDeprecated = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// taskID reads the task id from the {id} path segment, falling back to the
+// deprecated id query parameter.
+func taskID(r *http.Request) (int, error) {
+	raw := r.PathValue("id")
+	if raw == "" {
+		raw = r.URL.Query().Get("id")
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, apperr.New(apperr.BadRequest, "invalid_task_id", fmt.Sprintf("invalid task id %q", raw))
+	}
+	return id, nil
+}
+
+func (h *Handlers) taskPath(id int) string {
+	return h.basePath + "/tasks/" + strconv.Itoa(id)
+}
+
+// Deprecated marks a response as coming from a deprecated ?id= alias
+// (RFC 9745) and links to the path-based successor.
+func (h *Handlers) Deprecated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", deprecationDate(QueryIDDeprecated))
+		if id, err := taskID(r); err == nil {
+			w.Header().Add("Link", "<"+h.taskPath(id)+`>; rel="successor-version"`)
+		}
+		next(w, r)
+	}
+}
+
+// VersionPolicy announces the retirement of an API version. Zero times are
+// left out of the headers.
+type VersionPolicy struct {
+	// Deprecation is when the version was, or will be, deprecated.
+	Deprecation time.Time
+	// Sunset is when the version is expected to stop answering.
+	Sunset time.Time
+	// Link points to migration notes for the version.
+	Link string
+}
+
+// Announce adds the policy to every response of next: a Deprecation header
+// (RFC 9745), a Sunset header (RFC 8594), and the link to the notes with
+// the matching relation.
+func (p VersionPolicy) Announce(next http.Handler) http.Handler {
+	if p.Deprecation.IsZero() && p.Sunset.IsZero() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !p.Deprecation.IsZero() {
+			w.Header().Set("Deprecation", deprecationDate(p.Deprecation))
+			if p.Link != "" {
+				w.Header().Add("Link", "<"+p.Link+`>; rel="deprecation"`)
+			}
+		}
+		if !p.Sunset.IsZero() {
+			w.Header().Set("Sunset", p.Sunset.UTC().Format(http.TimeFormat))
+			if p.Link != "" {
+				w.Header().Add("Link", "<"+p.Link+`>; rel="sunset"`)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// deprecationDate formats t as an RFC 9745 structured-field date.
+func deprecationDate(t time.Time) string {
+	return "@" + strconv.FormatInt(t.Unix(), 10)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// Serializer shapes the responses of one API version. Every version runs
+// the same handlers over the same service; only the documents differ.
+type Serializer interface {
+	// Version names the API version, such as "v1".
+	Version() string
+	// Body is the document written for a successful response carrying data.
+	Body(data any, meta Meta) any
+	// Error writes a failed response described by p.
+	Error(w http.ResponseWriter, p Problem)
+	// Deleted writes the response to a successful delete.
+	Deleted(w http.ResponseWriter)
+}
+
+// Meta describes a response beyond its data.
+type Meta struct {
+	Version string `json:"version"`
+	// Page is set when a listing is split into pages.
+	Page *PageMeta `json:"page,omitempty"`
+}
+
+// PageMeta is the paging state of a listing.
+type PageMeta struct {
+	Limit int `json:"limit"`
+	// Next is the URL of the following page; empty on the last one.
+	Next string `json:"next,omitempty"`
+}
+
+var (
+	// V1 is the original contract: bare JSON documents, a {tasks, next}
+	// object for paged listings and problem+json errors.
+	V1 Serializer = v1Serializer{}
+	// V2 wraps every response in a {data, meta, errors} envelope; its
+	// errors are problem+json with the envelope members as extensions.
+	V2 Serializer = v2Serializer{}
+)
+
+type v1Serializer struct{}
+
+func (v1Serializer) Version() string { return "v1" }
+
+func (v1Serializer) Body(data any, meta Meta) any {
+	if meta.Page != nil {
+		return taskPage{Tasks: data, Next: meta.Page.Next}
+	}
+	return data
+}
+
+func (v1Serializer) Error(w http.ResponseWriter, p Problem) {
+	writeProblem(w, p)
+}
+
+func (v1Serializer) Deleted(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Task deleted successfully"))
+}
+
+type v2Serializer struct{}
+
+// envelope is the document of a successful v2 response.
+type envelope struct {
+	Data any  `json:"data"`
+	Meta Meta `json:"meta"`
+}
+
+// problemEnvelope is the document of a failed v2 response: a problem with
+// the envelope's members as extensions, so v2 clients read every failure
+// the same way. Errors lists the problem itself and takes the place of its
+// own errors member; the invalid fields are inside it.
+type problemEnvelope struct {
+	Problem
+	Data   any       `json:"data"`
+	Meta   Meta      `json:"meta"`
+	Errors []Problem `json:"errors"`
+}
+
+func (v2Serializer) Version() string { return "v2" }
+
+func (s v2Serializer) Body(data any, meta Meta) any {
+	meta.Version = s.Version()
+	return envelope{Data: data, Meta: meta}
+}
+
+func (s v2Serializer) Error(w http.ResponseWriter, p Problem) {
+	writeProblemDocument(w, p.Status, problemEnvelope{Problem: p, Meta: Meta{Version: s.Version()}, Errors: []Problem{p}})
+}
+
+func (v2Serializer) Deleted(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"task-manager/internal/apperr"
+	"task-manager/internal/models"
+)
+
+// taskStatus is the /tasks/{id}/status sub-resource.
+type taskStatus struct {
+	Status      models.Status `json:"status"`
+	StartedAt   *time.Time    `json:"started_at,omitempty"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty"`
+}
+
+func statusOf(task models.Task) taskStatus {
+	return taskStatus{Status: task.Status, StartedAt: task.StartedAt, CompletedAt: task.CompletedAt}
+}
+
+// GetTaskStatus returns the status of a task and its lifecycle timestamps.
+// The ETag is the task's, so it can guard a following PUT.
+func (h *Handlers) GetTaskStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := taskID(r)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	task, err := h.taskSvc.GetTask(r.Context(), id)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(task))
+	h.writeJSON(w, http.StatusOK, statusOf(task))
+}
+
+// SetTaskStatus moves a task to the status in the body, leaving the rest of
+// the task as it is. The transition table applies as for any update.
+func (h *Handlers) SetTaskStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := taskID(r)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	var body taskStatus
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	// The lifecycle timestamps follow the status and are never taken from
+	// the client.
+	if err := h.checkServerFields(&models.Task{StartedAt: body.StartedAt, CompletedAt: body.CompletedAt}, ""); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if body.Status == "" {
+		h.fail(w, r, apperr.Invalid(models.NewValidationError("/status", "status is required")))
+		return
+	}
+
+	precondition := writePreconditions(r)
+	task, err := h.taskSvc.UpdateTask(r.Context(), id, func(current models.Task) (models.Task, error) {
+		if precondition != nil {
+			if err := precondition(current); err != nil {
+				return models.Task{}, err
+			}
+		}
+		current.Status = body.Status
+		return current, nil
+	})
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(task))
+	h.writeJSON(w, http.StatusOK, statusOf(task))
+}
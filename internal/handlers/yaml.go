@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// yamlNode is a JSON value with object members kept in document order.
+type yamlNode struct {
+	scalar string // JSON text of a scalar; empty for containers
+	keys   []string
+	values []*yamlNode
+	list   bool
+}
+
+func (n *yamlNode) container() bool {
+	return n.scalar == ""
+}
+
+func (n *yamlNode) empty() bool {
+	return n.container() && len(n.values) == 0
+}
+
+var plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// encodeYAML renders v as a YAML 1.2 block document. v is encoded to JSON
+// first, so json tags and omitempty apply, and member order is kept. Strings
+// are written double-quoted, which YAML reads with JSON escaping rules.
+func encodeYAML(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := readYAMLNode(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if !root.container() || root.empty() {
+		writeYAMLScalar(&buf, root)
+		buf.WriteByte('\n')
+	} else {
+		writeYAMLBlock(&buf, root, 0)
+	}
+	return buf.Bytes(), nil
+}
+
+func readYAMLNode(dec *json.Decoder) (*yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		n := &yamlNode{list: tok == '['}
+		for dec.More() {
+			if !n.list {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+			value, err := readYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case string:
+		quoted, err := json.Marshal(tok)
+		return &yamlNode{scalar: string(quoted)}, err
+	case nil:
+		return &yamlNode{scalar: "null"}, nil
+	default:
+		return &yamlNode{scalar: fmt.Sprint(tok)}, nil
+	}
+}
+
+func writeYAMLScalar(buf *bytes.Buffer, n *yamlNode) {
+	switch {
+	case !n.container():
+		buf.WriteString(n.scalar)
+	case n.list:
+		buf.WriteString("[]")
+	default:
+		buf.WriteString("{}")
+	}
+}
+
+// writeYAMLBlock writes the members or items of a non-empty container, each
+// on its own line at the given indentation.
+func writeYAMLBlock(buf *bytes.Buffer, n *yamlNode, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for i, value := range n.values {
+		buf.WriteString(pad)
+		if n.list {
+			buf.WriteString("-")
+		} else {
+			key := n.keys[i]
+			if !plainKey.MatchString(key) {
+				quoted, _ := json.Marshal(key)
+				key = string(quoted)
+			}
+			buf.WriteString(key + ":")
+		}
+
+		switch {
+		case !value.container() || value.empty():
+			buf.WriteByte(' ')
+			writeYAMLScalar(buf, value)
+			buf.WriteByte('\n')
+		case n.list && !value.list:
+			// A mapping inside a sequence starts on the dash line.
+			var item bytes.Buffer
+			writeYAMLBlock(&item, value, indent+2)
+			buf.WriteByte(' ')
+			buf.Write(item.Bytes()[indent+2:])
+		default:
+			buf.WriteByte('\n')
+			writeYAMLBlock(buf, value, indent+2)
+		}
+	}
+}
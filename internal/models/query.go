@@ -0,0 +1,295 @@
+package models
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Op is a comparison operator used by a Filter.
+type Op string
+
+const (
+	OpEq       Op = "="
+	OpNe       Op = "!="
+	OpGt       Op = ">"
+	OpGe       Op = ">="
+	OpLt       Op = "<"
+	OpLe       Op = "<="
+	OpContains Op = "~="
+)
+
+type fieldKind int
+
+const (
+	kindInt fieldKind = iota
+	kindString
+	kindTime
+)
+
+// taskFields maps the JSON name of every filterable and sortable task field
+// to its kind.
+var taskFields = map[string]fieldKind{
+	"id":           kindInt,
+	"title":        kindString,
+	"description":  kindString,
+	"status":       kindString,
+	"priority":     kindInt,
+	"due_at":       kindTime,
+	"version":      kindInt,
+	"created_at":   kindTime,
+	"updated_at":   kindTime,
+	"started_at":   kindTime,
+	"completed_at": kindTime,
+}
+
+// TaskFields lists the JSON names of the task fields, in serialization order.
+var TaskFields = []string{
+	"id", "title", "description", "status", "priority", "due_at", "version",
+	"created_at", "updated_at", "started_at", "completed_at",
+}
+
+// fieldValue returns the value of the named field. It reports false for an
+// unset optional timestamp.
+func fieldValue(t *Task, name string) (any, bool) {
+	timeValue := func(v *time.Time) (any, bool) {
+		if v == nil {
+			return nil, false
+		}
+		return *v, true
+	}
+	switch name {
+	case "id":
+		return t.ID, true
+	case "title":
+		return t.Title, true
+	case "description":
+		return t.Description, true
+	case "status":
+		return string(t.Status), true
+	case "priority":
+		return t.Priority, true
+	case "due_at":
+		return timeValue(t.DueAt)
+	case "version":
+		return t.Version, true
+	case "created_at":
+		return t.CreatedAt, true
+	case "updated_at":
+		return t.UpdatedAt, true
+	case "started_at":
+		return timeValue(t.StartedAt)
+	case "completed_at":
+		return timeValue(t.CompletedAt)
+	}
+	return nil, false
+}
+
+func compare(a, b any) int {
+	switch a := a.(type) {
+	case int:
+		return cmp.Compare(a, b.(int))
+	case string:
+		return cmp.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+// Filter keeps the tasks whose field compares to one of its values with Op.
+type Filter struct {
+	Field  string
+	Op     Op
+	values []any
+}
+
+// NewFilter parses a filter. A comma-separated value with OpEq matches any
+// of the listed values. Timestamps are RFC 3339 or a plain YYYY-MM-DD date.
+func NewFilter(field string, op Op, value string) (Filter, error) {
+	kind, ok := taskFields[field]
+	if !ok {
+		return Filter{}, invalid("unknown filter field %q", field)
+	}
+	switch op {
+	case OpEq, OpNe:
+	case OpGt, OpGe, OpLt, OpLe:
+		if field == "status" {
+			return Filter{}, invalid("operator %s is not supported for status", op)
+		}
+	case OpContains:
+		if kind != kindString {
+			return Filter{}, invalid("operator %s is only supported for text fields", op)
+		}
+	default:
+		return Filter{}, invalid("unknown operator %q", op)
+	}
+
+	raw := []string{value}
+	if op == OpEq {
+		raw = strings.Split(value, ",")
+	}
+	f := Filter{Field: field, Op: op}
+	for _, s := range raw {
+		v, err := parseValue(field, kind, s)
+		if err != nil {
+			return Filter{}, err
+		}
+		f.values = append(f.values, v)
+	}
+	return f, nil
+}
+
+func parseValue(field string, kind fieldKind, s string) (any, error) {
+	switch kind {
+	case kindInt:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, invalid("%s must be an integer, got %q", field, s)
+		}
+		return n, nil
+	case kindTime:
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse(time.DateOnly, s); err == nil {
+			return t, nil
+		}
+		return nil, invalid("%s must be an RFC 3339 time or a YYYY-MM-DD date, got %q", field, s)
+	}
+	if field == "status" && !Status(s).Valid() {
+		return nil, invalid("unknown status %q", s)
+	}
+	return s, nil
+}
+
+// Match reports whether the task passes the filter. An unset optional field
+// never matches.
+func (f Filter) Match(t *Task) bool {
+	v, ok := fieldValue(t, f.Field)
+	if !ok {
+		return false
+	}
+	switch f.Op {
+	case OpEq:
+		return slices.ContainsFunc(f.values, func(want any) bool { return compare(v, want) == 0 })
+	case OpContains:
+		return strings.Contains(strings.ToLower(v.(string)), strings.ToLower(f.values[0].(string)))
+	}
+	c := compare(v, f.values[0])
+	switch f.Op {
+	case OpNe:
+		return c != 0
+	case OpGt:
+		return c > 0
+	case OpGe:
+		return c >= 0
+	case OpLt:
+		return c < 0
+	case OpLe:
+		return c <= 0
+	}
+	return false
+}
+
+func (f Filter) String() string {
+	values := make([]string, len(f.values))
+	for i, v := range f.values {
+		if t, ok := v.(time.Time); ok {
+			values[i] = t.Format(time.RFC3339)
+		} else {
+			values[i] = fmt.Sprint(v)
+		}
+	}
+	return f.Field + string(f.Op) + strings.Join(values, ",")
+}
+
+// SortKey orders tasks by one field.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a comma-separated list of field names, each optionally
+// prefixed with "-" for descending order, e.g. "-priority,created_at".
+func ParseSort(s string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, name := range strings.Split(s, ",") {
+		key := SortKey{Field: strings.TrimSpace(name)}
+		if rest, ok := strings.CutPrefix(key.Field, "-"); ok {
+			key.Field, key.Desc = rest, true
+		}
+		if _, ok := taskFields[key.Field]; !ok {
+			return nil, invalid("unknown sort field %q", key.Field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// TaskQuery selects tasks when listing them.
+type TaskQuery struct {
+	// Overdue keeps only open tasks whose due date has passed.
+	Overdue bool
+	// Filters must all match.
+	Filters []Filter
+	// Sort orders the result; ties, and an empty Sort, fall back to ID order.
+	Sort []SortKey
+	// After resumes the listing after this task in query order. Only its ID
+	// and sort fields are consulted, so tasks changed or deleted since the
+	// previous page neither shift nor repeat the remaining ones.
+	After *Task
+	// Limit caps the number of tasks returned; zero means no limit.
+	Limit int
+}
+
+// Match reports whether the task is selected by the query at time now.
+func (q TaskQuery) Match(t *Task, now time.Time) bool {
+	if q.After != nil && q.Compare(t, q.After) <= 0 {
+		return false
+	}
+	if q.Overdue && !t.Overdue(now) {
+		return false
+	}
+	for _, f := range q.Filters {
+		if !f.Match(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare orders two tasks by the query's sort keys, then by ID. Unset
+// optional fields sort last regardless of direction.
+func (q TaskQuery) Compare(a, b *Task) int {
+	for _, key := range q.Sort {
+		av, aok := fieldValue(a, key.Field)
+		bv, bok := fieldValue(b, key.Field)
+		switch {
+		case !aok && !bok:
+			continue
+		case !aok:
+			return 1
+		case !bok:
+			return -1
+		}
+		c := compare(av, bv)
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// SortTasks orders tasks as Compare does.
+func (q TaskQuery) SortTasks(tasks []Task) {
+	slices.SortFunc(tasks, func(a, b Task) int {
+		return q.Compare(&a, &b)
+	})
+}
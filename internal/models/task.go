@@ -1,18 +1,101 @@
 package models
 
 import (
-	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"task-manager/internal/apperr"
+)
+
+type Status string
+
+const (
+	StatusTodo       Status = "todo"
+	StatusInProgress Status = "in_progress"
+	StatusBlocked    Status = "blocked"
+	StatusDone       Status = "done"
+	StatusCancelled  Status = "cancelled"
+)
+
+var Statuses = []Status{StatusTodo, StatusInProgress, StatusBlocked, StatusDone, StatusCancelled}
+
+func (s Status) Valid() bool {
+	return slices.Contains(Statuses, s)
+}
+
+const (
+	PriorityMin = 0
+	PriorityMax = 5
 )
 
 type Task struct {
 	ID          int    `json:"id"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	Status      Status `json:"status"`
+	// Priority ranges from PriorityMin (none) to PriorityMax (most urgent).
+	Priority int        `json:"priority"`
+	DueAt    *time.Time `json:"due_at,omitempty"`
+	// Version starts at 1 and is incremented by every update.
+	Version     int        `json:"version"`
+	CreatedAt   time.Time  `json:"created_at,omitzero"`
+	UpdatedAt   time.Time  `json:"updated_at,omitzero"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Violation is one broken rule. Pointer locates the offending value in the
+// task's JSON form (RFC 6901), e.g. "/title"; it is empty for rules that
+// are not about a single value.
+type Violation struct {
+	Pointer string
+	Message string
+}
+
+// ValidationError reports every rule the input breaks.
+type ValidationError struct {
+	Violations []Violation
 }
 
+// NewValidationError reports a single violation at pointer.
+func NewValidationError(pointer, format string, args ...any) *ValidationError {
+	return &ValidationError{Violations: []Violation{{Pointer: pointer, Message: fmt.Sprintf(format, args...)}}}
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// FieldErrors lets apperr.Invalid report the located violations to clients.
+func (e *ValidationError) FieldErrors() []apperr.FieldError {
+	var fields []apperr.FieldError
+	for _, v := range e.Violations {
+		if v.Pointer != "" {
+			fields = append(fields, apperr.FieldError{Pointer: v.Pointer, Detail: v.Message})
+		}
+	}
+	return fields
+}
+
+func invalid(format string, args ...any) error {
+	return NewValidationError("", format, args...)
+}
+
+// Validate checks the task against DefaultValidator.
 func (t *Task) Validate() error {
-	if len(t.Title) == 0 {
-		return errors.New("title is required")
+	return DefaultValidator.Validate(t)
+}
+
+// Overdue reports whether the task is past its due date at now and still open.
+func (t *Task) Overdue(now time.Time) bool {
+	if t.DueAt == nil || t.Status == StatusDone || t.Status == StatusCancelled {
+		return false
 	}
-	return nil
+	return t.DueAt.Before(now)
 }
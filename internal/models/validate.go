@@ -0,0 +1,151 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextLimits bounds a text field. Lengths count characters, not bytes; a
+// zero MaxLength means no upper bound. Pattern, when set, must match the
+// whole value.
+type TextLimits struct {
+	MinLength int
+	MaxLength int
+	Pattern   string
+}
+
+// Limits are the per-field rules a deployment may tighten.
+type Limits struct {
+	Title       TextLimits
+	Description TextLimits
+}
+
+// DefaultLimits apply when nothing else is configured.
+var DefaultLimits = Limits{
+	Title:       TextLimits{MinLength: 1, MaxLength: 200},
+	Description: TextLimits{MaxLength: 10000},
+}
+
+// DefaultValidator checks tasks against DefaultLimits.
+var DefaultValidator = mustValidator(DefaultLimits)
+
+type textRule struct {
+	limits  TextLimits
+	pattern *regexp.Regexp
+	// multiline allows line breaks and tabs.
+	multiline bool
+}
+
+// Validator checks a task against every model rule and reports all the
+// violations at once.
+type Validator struct {
+	title       textRule
+	description textRule
+}
+
+// NewValidator returns a validator enforcing limits.
+func NewValidator(limits Limits) (*Validator, error) {
+	title, err := newTextRule("title", limits.Title, false)
+	if err != nil {
+		return nil, err
+	}
+	if title.limits.MinLength < 1 {
+		// A title is always required.
+		title.limits.MinLength = 1
+	}
+	description, err := newTextRule("description", limits.Description, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{title: title, description: description}, nil
+}
+
+func mustValidator(limits Limits) *Validator {
+	v, err := NewValidator(limits)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func newTextRule(field string, limits TextLimits, multiline bool) (textRule, error) {
+	rule := textRule{limits: limits, multiline: multiline}
+	if limits.MinLength < 0 || limits.MaxLength < 0 {
+		return rule, fmt.Errorf("%s: lengths must not be negative", field)
+	}
+	if limits.MaxLength > 0 && limits.MinLength > limits.MaxLength {
+		return rule, fmt.Errorf("%s: min_length %d is above max_length %d", field, limits.MinLength, limits.MaxLength)
+	}
+	if limits.Pattern != "" {
+		pattern, err := regexp.Compile(`^(?:` + limits.Pattern + `)$`)
+		if err != nil {
+			return rule, fmt.Errorf("%s: invalid pattern: %w", field, err)
+		}
+		rule.pattern = pattern
+	}
+	return rule, nil
+}
+
+// Validate checks the task content. The due date is compared with CreatedAt
+// only once the task has one, i.e. after the repository has stamped it.
+func (v *Validator) Validate(t *Task) error {
+	var violations []Violation
+	add := func(pointer, format string, args ...any) {
+		violations = append(violations, Violation{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+
+	v.title.check("title", t.Title, add)
+	v.description.check("description", t.Description, add)
+	if t.Status != "" && !t.Status.Valid() {
+		add("/status", "unknown status %q", t.Status)
+	}
+	if t.Priority < PriorityMin || t.Priority > PriorityMax {
+		add("/priority", "priority must be between %d and %d", PriorityMin, PriorityMax)
+	}
+	if t.DueAt != nil && !t.CreatedAt.IsZero() && t.DueAt.Before(t.CreatedAt) {
+		add("/due_at", "due date must not be before the creation time")
+	}
+	if t.StartedAt != nil && t.CompletedAt != nil && t.CompletedAt.Before(*t.StartedAt) {
+		add("/completed_at", "completion time must not be before the start time")
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func (r textRule) check(field, value string, add func(pointer, format string, args ...any)) {
+	pointer := "/" + field
+	if !utf8.ValidString(value) {
+		add(pointer, "%s must be valid UTF-8", field)
+		return
+	}
+	length := utf8.RuneCountInString(value)
+	switch {
+	case length == 0 && r.limits.MinLength > 0:
+		add(pointer, "%s is required", field)
+		return
+	case length < r.limits.MinLength:
+		add(pointer, "%s must be at least %d characters", field, r.limits.MinLength)
+	case r.limits.MaxLength > 0 && length > r.limits.MaxLength:
+		add(pointer, "%s must be at most %d characters", field, r.limits.MaxLength)
+	}
+	if i := strings.IndexFunc(value, r.disallowed); i >= 0 {
+		c, _ := utf8.DecodeRuneInString(value[i:])
+		add(pointer, "%s must not contain the control character %U", field, c)
+	}
+	if r.pattern != nil && !r.pattern.MatchString(value) {
+		add(pointer, "%s must match the pattern %s", field, r.limits.Pattern)
+	}
+}
+
+func (r textRule) disallowed(c rune) bool {
+	if r.multiline && (c == '\n' || c == '\r' || c == '\t') {
+		return false
+	}
+	return unicode.IsControl(c)
+}
@@ -0,0 +1,328 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const JSONPatchContentType = "application/json-patch+json"
+
+// ErrTestFailed is returned when a "test" operation does not match.
+var ErrTestFailed = errors.New("test operation failed")
+
+// Operation is a single RFC 6902 operation. Value stays raw so that an
+// explicit null can be told apart from a missing value.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch applies an RFC 6902 JSON Patch to doc. Operations are applied in
+// order to a private copy, so on error the caller's document is unaffected.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []Operation
+	dec := json.NewDecoder(bytes.NewReader(patch))
+	if err := dec.Decode(&ops); err != nil {
+		return nil, fmt.Errorf("invalid json patch: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("invalid json patch: unexpected data after JSON value")
+	}
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	for i, op := range ops {
+		target, err = apply(target, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func apply(doc any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("missing value")
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if _, err := get(doc, path); err != nil {
+				return nil, err
+			}
+			doc, _, err = remove(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, ErrTestFailed
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+		var value any
+		if op.Op == "move" {
+			if len(from) < len(path) && isPrefix(from, path) {
+				return nil, fmt.Errorf("cannot move a value into one of its children")
+			}
+			doc, value, err = remove(doc, from)
+		} else {
+			value, err = get(doc, from)
+			value = deepCopy(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	limit := length - 1
+	if allowEnd {
+		limit = length
+	}
+	if i > limit {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path member %q not found", token)
+			}
+			doc = value
+		case []any:
+			i, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("cannot traverse into a scalar at %q", token)
+		}
+	}
+	return doc, nil
+}
+
+// add inserts value at path and returns the updated document; containers may
+// be reallocated, so callers must use the returned value.
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			node[token] = value
+			return node, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("path member %q not found", token)
+		}
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+	case []any:
+		i, err := arrayIndex(token, len(node), len(rest) == 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		child, err := add(node[i], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+	default:
+		return nil, fmt.Errorf("cannot add into a scalar at %q", token)
+	}
+}
+
+// remove deletes the value at path and returns the updated document along
+// with the removed value.
+func remove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path member %q not found", token)
+		}
+		if len(rest) == 0 {
+			delete(node, token)
+			return node, child, nil
+		}
+		child, removed, err := remove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[token] = child
+		return node, removed, nil
+	case []any:
+		i, err := arrayIndex(token, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := node[i]
+			return append(node[:i], node[i+1:]...), removed, nil
+		}
+		child, removed, err := remove(node[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[i] = child
+		return node, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot remove from a scalar at %q", token)
+	}
+}
+
+func deepCopy(v any) any {
+	switch node := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(node))
+		for k, v := range node {
+			c[k] = deepCopy(v)
+		}
+		return c
+	case []any:
+		c := make([]any, len(node))
+		for i, v := range node {
+			c[i] = deepCopy(v)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// equal compares JSON values the way the "test" operation requires: numbers
+// by value, objects regardless of member order.
+func equal(a, b any) bool {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			w, ok := bv[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		if aerr != nil || berr != nil {
+			return av == bv
+		}
+		return af == bf
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,75 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+)
+
+// Cases from RFC 6902, Appendix A.
+func TestJSONPatch(t *testing.T) {
+	cases := []struct {
+		name, doc, patch, want string
+	}{
+		{"add object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"remove object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace value", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"move value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"test success", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{"add nested member", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{"add to array end", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{"escaped pointer", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, `{"~1":10}`},
+		{"copy value", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		{"add null value", `{"foo":"bar"}`, `[{"op":"add","path":"/foo","value":null}]`, `{"foo":null}`},
+	}
+	for _, c := range cases {
+		got, err := JSONPatch([]byte(c.doc), []byte(c.patch))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if !jsonEqual(t, got, []byte(c.want)) {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	cases := []struct {
+		name, doc, patch string
+	}{
+		{"remove missing member", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`},
+		{"replace missing member", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`},
+		{"add to missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+		{"array index out of range", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/5","value":"qux"}]`},
+		{"leading zero index", `{"foo":["bar","baz"]}`, `[{"op":"remove","path":"/foo/01"}]`},
+		{"missing value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`},
+		{"unknown op", `{"foo":"bar"}`, `[{"op":"frobnicate","path":"/foo"}]`},
+		{"move into child", `{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`},
+		{"invalid pointer", `{"foo":"bar"}`, `[{"op":"remove","path":"foo"}]`},
+		{"not an array", `{"foo":"bar"}`, `{"op":"remove","path":"/foo"}`},
+	}
+	for _, c := range cases {
+		if _, err := JSONPatch([]byte(c.doc), []byte(c.patch)); err == nil {
+			t.Errorf("%s: expected error", c.name)
+		}
+	}
+}
+
+func TestJSONPatchTestFailure(t *testing.T) {
+	doc := `{"status":"todo","title":"a"}`
+	patch := `[{"op":"replace","path":"/title","value":"b"},{"op":"test","path":"/status","value":"done"}]`
+	_, err := JSONPatch([]byte(doc), []byte(patch))
+	if !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("expected ErrTestFailed, got %v", err)
+	}
+}
+
+func TestJSONPatchTestComparesNumbersByValue(t *testing.T) {
+	_, err := JSONPatch([]byte(`{"n":1}`), []byte(`[{"op":"test","path":"/n","value":1.0}]`))
+	if err != nil {
+		t.Errorf("expected 1 and 1.0 to be equal, got %v", err)
+	}
+}
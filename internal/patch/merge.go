@@ -0,0 +1,58 @@
+// Package patch applies JSON patch documents to JSON values.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+const MergePatchContentType = "application/merge-patch+json"
+
+// MergePatch applies an RFC 7386 JSON Merge Patch to doc and returns the
+// resulting document.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any)
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergeValue(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// decode parses a single JSON value, keeping numbers as json.Number so that
+// values round-trip unchanged.
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return v, nil
+}
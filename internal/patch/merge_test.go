@@ -0,0 +1,61 @@
+package patch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Cases from RFC 7386, Appendix A.
+func TestMergePatch(t *testing.T) {
+	cases := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, c := range cases {
+		got, err := MergePatch([]byte(c.doc), []byte(c.patch))
+		if err != nil {
+			t.Errorf("MergePatch(%s, %s): unexpected error: %v", c.doc, c.patch, err)
+			continue
+		}
+		if !jsonEqual(t, got, []byte(c.want)) {
+			t.Errorf("MergePatch(%s, %s) = %s, want %s", c.doc, c.patch, got, c.want)
+		}
+	}
+}
+
+func TestMergePatchInvalid(t *testing.T) {
+	if _, err := MergePatch([]byte(`{}`), []byte(`{"a":`)); err == nil {
+		t.Errorf("expected error for malformed patch")
+	}
+	if _, err := MergePatch([]byte(`{}`), []byte(`{} {}`)); err == nil {
+		t.Errorf("expected error for trailing data")
+	}
+}
+
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -2,25 +2,47 @@ package repository
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"time"
 
+	"task-manager/internal/apperr"
 	"task-manager/internal/models"
 	"task-manager/internal/store"
 	"task-manager/pkg/logger"
 )
 
 var (
-	ErrTaskNotFound = errors.New("task not found")
+	ErrTaskNotFound = apperr.New(apperr.NotFound, "task_not_found", "task not found")
 )
 
 type Repository struct {
-	store *store.Store
+	store     store.Driver
+	now       func() time.Time
+	validator *models.Validator
 }
 
-func NewRepository(store *store.Store) *Repository {
-	return &Repository{store: store}
+type Option func(*Repository)
+
+// WithClock sets the source of the CreatedAt and UpdatedAt timestamps.
+func WithClock(now func() time.Time) Option {
+	return func(r *Repository) {
+		r.now = now
+	}
+}
+
+// WithValidator sets the rules every stored task must pass; the default is
+// models.DefaultValidator.
+func WithValidator(v *models.Validator) Option {
+	return func(r *Repository) {
+		r.validator = v
+	}
+}
+
+func NewRepository(store store.Driver, opts ...Option) *Repository {
+	r := &Repository{store: store, now: time.Now, validator: models.DefaultValidator}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *Repository) CreateTask(ctx context.Context, task models.Task) (models.Task, error) {
@@ -31,10 +53,17 @@ func (r *Repository) CreateTask(ctx context.Context, task models.Task) (models.T
 	case <-ctx.Done():
 		return models.Task{}, ctx.Err()
 	default:
-		task.ID = r.store.NextID()
-		r.store.Set(task.ID, task)
-		logger.LogInfo(fmt.Sprintf("task with title %s created", task.Title))
-		return task, nil
+		var created models.Task
+		err := r.store.Update(func(tx store.Tx) error {
+			var err error
+			created, err = r.tx(ctx, tx).CreateTask(task)
+			return err
+		})
+		if err != nil {
+			return models.Task{}, err
+		}
+		logger.InfoContext(ctx, "task created", "task_id", created.ID, "title", created.Title)
+		return created, nil
 	}
 }
 
@@ -46,16 +75,32 @@ func (r *Repository) GetTask(ctx context.Context, id int) (models.Task, error) {
 	case <-ctx.Done():
 		return models.Task{}, ctx.Err()
 	default:
-		task, ok := r.store.Get(id)
-		if !ok {
-			logger.LogInfo(fmt.Sprintf("task with id %d not found", id))
-			return models.Task{}, ErrTaskNotFound
+		var task models.Task
+		err := r.store.View(func(tx store.ReadTx) error {
+			var err error
+			task, err = getTask(ctx, tx, id)
+			return err
+		})
+		if err != nil {
+			return models.Task{}, err
 		}
 		return task, nil
 	}
 }
 
-func (r *Repository) GetTasks(ctx context.Context) ([]models.Task, error) {
+func getTask(ctx context.Context, tx store.ReadTx, id int) (models.Task, error) {
+	task, ok, err := tx.Get(id)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if !ok {
+		logger.DebugContext(ctx, "task not found", "task_id", id)
+		return models.Task{}, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+func (r *Repository) GetTasks(ctx context.Context, query models.TaskQuery) ([]models.Task, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -63,12 +108,80 @@ func (r *Repository) GetTasks(ctx context.Context) ([]models.Task, error) {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
-		tasks := r.store.GetAllTasks()
+		// In ID order the scan can start at the cursor and stop once the page
+		// is full; any other order needs every match before sorting.
+		now := r.now()
+		sorted := len(query.Sort) > 0
+		after := 0
+		if query.After != nil && !sorted {
+			after = query.After.ID
+		}
+		tasks := make([]models.Task, 0)
+		err := r.store.View(func(tx store.ReadTx) error {
+			return tx.Scan(after, func(task models.Task) bool {
+				if query.Match(&task, now) {
+					tasks = append(tasks, task)
+				}
+				return sorted || query.Limit == 0 || len(tasks) < query.Limit
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		if sorted {
+			query.SortTasks(tasks)
+			if query.Limit > 0 && len(tasks) > query.Limit {
+				tasks = tasks[:query.Limit]
+			}
+		}
 		return tasks, nil
 	}
 }
 
-func (r *Repository) DeleteTask(ctx context.Context, id int) error {
+// ExportTasks calls fn for every task in ID order, straight from the store
+// iterator. It stops at the first error returned by fn or once ctx is done.
+func (r *Repository) ExportTasks(ctx context.Context, fn func(models.Task) error) error {
+	var fnErr error
+	err := r.store.Scan(0, func(task models.Task) bool {
+		if fnErr = ctx.Err(); fnErr != nil {
+			return false
+		}
+		fnErr = fn(task)
+		return fnErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// UpdateTask replaces the task with the result of update, reading and writing
+// it in one storage transaction so concurrent updates cannot interleave.
+func (r *Repository) UpdateTask(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return models.Task{}, ctx.Err()
+	default:
+		var updated models.Task
+		err := r.store.Update(func(tx store.Tx) error {
+			var err error
+			updated, err = r.tx(ctx, tx).UpdateTask(id, update)
+			return err
+		})
+		if err != nil {
+			return models.Task{}, err
+		}
+		logger.InfoContext(ctx, "task updated", "task_id", id)
+		return updated, nil
+	}
+}
+
+// DeleteTask removes the task. A non-nil precondition is checked against the
+// stored task in the same transaction and aborts the delete if it fails.
+func (r *Repository) DeleteTask(ctx context.Context, id int, precondition func(models.Task) error) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -76,12 +189,101 @@ func (r *Repository) DeleteTask(ctx context.Context, id int) error {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		deleted := r.store.Delete(id)
-		if !deleted {
-			logger.LogInfo(fmt.Sprintf("task %d not found", id))
-			return ErrTaskNotFound
+		err := r.store.Update(func(tx store.Tx) error {
+			return r.tx(ctx, tx).DeleteTask(id, precondition)
+		})
+		if err != nil {
+			return err
 		}
-		logger.LogInfo(fmt.Sprintf("task %d deleted", id))
+		logger.InfoContext(ctx, "task deleted", "task_id", id)
 		return nil
 	}
 }
+
+// Batch runs fn in a single storage transaction. Changes made through tx are
+// committed together when fn returns nil and discarded otherwise.
+func (r *Repository) Batch(ctx context.Context, fn func(tx *Tx) error) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return r.store.Update(func(tx store.Tx) error {
+			return fn(r.tx(ctx, tx))
+		})
+	}
+}
+
+// Tx changes tasks inside a storage transaction with the same rules as the
+// Repository methods of the same name.
+type Tx struct {
+	r  *Repository
+	tx store.Tx
+	// ctx is the context of the request the transaction serves, for logging.
+	ctx context.Context
+}
+
+func (r *Repository) tx(ctx context.Context, tx store.Tx) *Tx {
+	return &Tx{r: r, tx: tx, ctx: ctx}
+}
+
+func (t *Tx) CreateTask(task models.Task) (models.Task, error) {
+	now := t.r.now()
+	task.CreatedAt, task.UpdatedAt = now, now
+	if err := t.r.validator.Validate(&task); err != nil {
+		return models.Task{}, apperr.Invalid(err)
+	}
+	id, err := t.tx.NextID()
+	if err != nil {
+		return models.Task{}, err
+	}
+	task.ID = id
+	task.Version = 1
+	if err := t.tx.Set(task.ID, task); err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+// GetTask reads the task, including changes made earlier in the transaction.
+func (t *Tx) GetTask(id int) (models.Task, error) {
+	return getTask(t.ctx, t.tx, id)
+}
+
+func (t *Tx) UpdateTask(id int, update func(models.Task) (models.Task, error)) (models.Task, error) {
+	task, err := getTask(t.ctx, t.tx, id)
+	if err != nil {
+		return models.Task{}, err
+	}
+	updated, err := update(task)
+	if err != nil {
+		return models.Task{}, err
+	}
+	updated.ID = id
+	updated.Version = task.Version + 1
+	updated.CreatedAt = task.CreatedAt
+	updated.UpdatedAt = t.r.now()
+	if err := t.r.validator.Validate(&updated); err != nil {
+		return models.Task{}, apperr.Invalid(err)
+	}
+	if err := t.tx.Set(id, updated); err != nil {
+		return models.Task{}, err
+	}
+	return updated, nil
+}
+
+func (t *Tx) DeleteTask(id int, precondition func(models.Task) error) error {
+	task, err := getTask(t.ctx, t.tx, id)
+	if err != nil {
+		return err
+	}
+	if precondition != nil {
+		if err := precondition(task); err != nil {
+			return err
+		}
+	}
+	_, err = t.tx.Delete(id)
+	return err
+}
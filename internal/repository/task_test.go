@@ -61,7 +61,7 @@ func TestGetTasks(t *testing.T) {
 	repo.CreateTask(ctx, models.Task{Title: "Task1"})
 	repo.CreateTask(ctx, models.Task{Title: "Task2"})
 
-	tasks, err := repo.GetTasks(ctx)
+	tasks, err := repo.GetTasks(ctx, models.TaskQuery{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -78,12 +78,12 @@ func TestDeleteTask(t *testing.T) {
 
 	task, _ := repo.CreateTask(ctx, models.Task{Title: "To delete"})
 
-	err := repo.DeleteTask(ctx, task.ID)
+	err := repo.DeleteTask(ctx, task.ID, nil)
 	if err != nil {
 		t.Errorf("unexpected error on delete: %v", err)
 	}
 
-	tasks, _ := repo.GetTasks(ctx)
+	tasks, _ := repo.GetTasks(ctx, models.TaskQuery{})
 	for _, tsk := range tasks {
 		if tsk.ID == task.ID {
 			t.Error("deleted task still present in store")
@@ -91,6 +91,103 @@ func TestDeleteTask(t *testing.T) {
 	}
 }
 
+func TestUpdateTask(t *testing.T) {
+	st := store.NewStore()
+	repo := NewRepository(st)
+
+	ctx := context.Background()
+
+	task, _ := repo.CreateTask(ctx, models.Task{Title: "Old title"})
+	updated, err := repo.UpdateTask(ctx, task.ID, func(current models.Task) (models.Task, error) {
+		current.Title = "New title"
+		current.ID = 999
+		return current, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.ID != task.ID {
+		t.Errorf("expected ID %d to be kept, got %d", task.ID, updated.ID)
+	}
+	retrievedTask, _ := repo.GetTask(ctx, task.ID)
+	if retrievedTask.Title != "New title" {
+		t.Errorf("expected title %q, got %q", "New title", retrievedTask.Title)
+	}
+}
+
+func TestUpdateTask_NotFound(t *testing.T) {
+	st := store.NewStore()
+	repo := NewRepository(st)
+
+	_, err := repo.UpdateTask(context.Background(), 42, func(task models.Task) (models.Task, error) {
+		return task, nil
+	})
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestUpdateTask_UpdateErrorLeavesTaskUntouched(t *testing.T) {
+	st := store.NewStore()
+	repo := NewRepository(st)
+
+	ctx := context.Background()
+
+	task, _ := repo.CreateTask(ctx, models.Task{Title: "Title"})
+	errRejected := errors.New("rejected")
+	_, err := repo.UpdateTask(ctx, task.ID, func(current models.Task) (models.Task, error) {
+		return models.Task{Title: "Changed"}, errRejected
+	})
+	if !errors.Is(err, errRejected) {
+		t.Fatalf("expected update error, got %v", err)
+	}
+	retrievedTask, _ := repo.GetTask(ctx, task.ID)
+	if retrievedTask.Title != "Title" {
+		t.Errorf("expected task to be untouched, got %q", retrievedTask.Title)
+	}
+}
+
+func TestUpdateTask_IncrementsVersion(t *testing.T) {
+	st := store.NewStore()
+	repo := NewRepository(st)
+
+	ctx := context.Background()
+
+	task, _ := repo.CreateTask(ctx, models.Task{Title: "Title", Version: 42})
+	if task.Version != 1 {
+		t.Fatalf("expected new task to have version 1, got %d", task.Version)
+	}
+	for want := 2; want <= 3; want++ {
+		updated, err := repo.UpdateTask(ctx, task.ID, func(current models.Task) (models.Task, error) {
+			current.Version = 0
+			return current, nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if updated.Version != want {
+			t.Errorf("expected version %d, got %d", want, updated.Version)
+		}
+	}
+}
+
+func TestDeleteTask_PreconditionFailed(t *testing.T) {
+	st := store.NewStore()
+	repo := NewRepository(st)
+
+	ctx := context.Background()
+
+	task, _ := repo.CreateTask(ctx, models.Task{Title: "Keep me"})
+	errStale := errors.New("stale")
+	err := repo.DeleteTask(ctx, task.ID, func(models.Task) error { return errStale })
+	if !errors.Is(err, errStale) {
+		t.Fatalf("expected precondition error, got %v", err)
+	}
+	if _, err := repo.GetTask(ctx, task.ID); err != nil {
+		t.Errorf("expected task to survive, got %v", err)
+	}
+}
+
 func TestCreateTask_ContextCanceled(t *testing.T) {
 	st := store.NewStore()
 	repo := NewRepository(st)
@@ -114,7 +211,7 @@ func TestGetTasks_ContextTimeout(t *testing.T) {
 
 	time.Sleep(10 * time.Millisecond)
 
-	_, err := repo.GetTasks(ctx)
+	_, err := repo.GetTasks(ctx, models.TaskQuery{})
 	if !errors.Is(err, context.DeadlineExceeded) {
 		t.Errorf("expected DeadlineExceeded error, got %v", err)
 	}
@@ -127,8 +224,227 @@ func TestDeleteTask_ContextCanceled(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	err := repo.DeleteTask(ctx, 123)
+	err := repo.DeleteTask(ctx, 123, nil)
 	if !errors.Is(err, context.Canceled) {
 		t.Errorf("expected context.Canceled error, got %v", err)
 	}
 }
+
+type failingDriver struct {
+	store.Driver
+	err error
+}
+
+// Update runs fn but fails to commit.
+func (d failingDriver) Update(fn func(tx store.Tx) error) error {
+	return d.Driver.Update(func(tx store.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return d.err
+	})
+}
+
+func TestCreateTask_StorageError(t *testing.T) {
+	errDisk := errors.New("disk full")
+	repo := NewRepository(failingDriver{Driver: store.NewStore(), err: errDisk})
+
+	_, err := repo.CreateTask(context.Background(), models.Task{Title: "Task"})
+	if !errors.Is(err, errDisk) {
+		t.Errorf("expected storage error, got %v", err)
+	}
+}
+
+func TestTimestampsFromClock(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := NewRepository(store.NewStore(), WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	task, err := repo.CreateTask(ctx, models.Task{Title: "Task"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !task.CreatedAt.Equal(now) || !task.UpdatedAt.Equal(now) {
+		t.Errorf("expected both timestamps to be %v, got %v and %v", now, task.CreatedAt, task.UpdatedAt)
+	}
+
+	created := now
+	now = now.Add(time.Hour)
+	updated, err := repo.UpdateTask(ctx, task.ID, func(current models.Task) (models.Task, error) {
+		current.CreatedAt = time.Time{}
+		return current, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !updated.CreatedAt.Equal(created) {
+		t.Errorf("expected created_at to be kept, got %v", updated.CreatedAt)
+	}
+	if !updated.UpdatedAt.Equal(now) {
+		t.Errorf("expected updated_at %v, got %v", now, updated.UpdatedAt)
+	}
+}
+
+func TestCreateTask_DueBeforeCreation(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := NewRepository(store.NewStore(), WithClock(func() time.Time { return now }))
+
+	due := now.Add(-time.Minute)
+	_, err := repo.CreateTask(context.Background(), models.Task{Title: "Task", DueAt: &due})
+	var invalid *models.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestCreateTask_ConfiguredLimits(t *testing.T) {
+	validator, err := models.NewValidator(models.Limits{
+		Title: models.TextLimits{MaxLength: 10, Pattern: `[A-Za-z ]+`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository(store.NewStore(), WithValidator(validator))
+
+	_, err = repo.CreateTask(context.Background(), models.Task{Title: "Release v2.0.1", Priority: 9})
+	var invalid *models.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	pointers := map[string]int{}
+	for _, v := range invalid.Violations {
+		pointers[v.Pointer]++
+	}
+	if pointers["/title"] != 2 || pointers["/priority"] != 1 {
+		t.Errorf("expected length and pattern violations on the title and one on the priority, got %+v", invalid.Violations)
+	}
+
+	if _, err := repo.CreateTask(context.Background(), models.Task{Title: "Release"}); err != nil {
+		t.Errorf("expected a title within the limits to pass, got %v", err)
+	}
+}
+
+func TestGetTasks_Overdue(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := NewRepository(store.NewStore(), WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	soon, later := now.Add(time.Hour), now.Add(48*time.Hour)
+	late, _ := repo.CreateTask(ctx, models.Task{Title: "Late", DueAt: &soon})
+	repo.CreateTask(ctx, models.Task{Title: "Done", Status: models.StatusDone, DueAt: &soon})
+	repo.CreateTask(ctx, models.Task{Title: "Later", DueAt: &later})
+	repo.CreateTask(ctx, models.Task{Title: "No due date"})
+
+	now = now.Add(2 * time.Hour)
+	tasks, err := repo.GetTasks(ctx, models.TaskQuery{Overdue: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != late.ID {
+		t.Errorf("expected only task %d to be overdue, got %v", late.ID, tasks)
+	}
+}
+
+func TestGetTasks_FilterAndSort(t *testing.T) {
+	repo := NewRepository(store.NewStore())
+	ctx := context.Background()
+
+	repo.CreateTask(ctx, models.Task{Title: "Write report", Priority: 3})
+	repo.CreateTask(ctx, models.Task{Title: "Review report", Priority: 5})
+	repo.CreateTask(ctx, models.Task{Title: "Lunch", Priority: 4})
+	repo.CreateTask(ctx, models.Task{Title: "Report bug", Priority: 3})
+
+	title, _ := models.NewFilter("title", models.OpContains, "REPORT")
+	priority, _ := models.NewFilter("priority", models.OpGe, "3")
+	sort, _ := models.ParseSort("-priority,title")
+	tasks, err := repo.GetTasks(ctx, models.TaskQuery{Filters: []models.Filter{title, priority}, Sort: sort})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var titles []string
+	for _, task := range tasks {
+		titles = append(titles, task.Title)
+	}
+	want := []string{"Review report", "Report bug", "Write report"}
+	if len(titles) != len(want) {
+		t.Fatalf("expected %v, got %v", want, titles)
+	}
+	for i := range want {
+		if titles[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, titles)
+		}
+	}
+}
+
+func TestGetTasks_Paged(t *testing.T) {
+	repo := NewRepository(store.NewStore())
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		repo.CreateTask(ctx, models.Task{Title: "Task"})
+	}
+
+	first, err := repo.GetTasks(ctx, models.TaskQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first) != 2 || first[0].ID != 1 || first[1].ID != 2 {
+		t.Fatalf("expected tasks 1 and 2, got %v", first)
+	}
+
+	// Deleting the last task of the page must not skip or repeat any task.
+	repo.DeleteTask(ctx, 2, nil)
+	rest, err := repo.GetTasks(ctx, models.TaskQuery{After: &first[1], Limit: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rest) != 3 || rest[0].ID != 3 {
+		t.Errorf("expected tasks 3 to 5, got %v", rest)
+	}
+}
+
+func TestExportTasks(t *testing.T) {
+	repo := NewRepository(store.NewStore())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < 5; i++ {
+		repo.CreateTask(ctx, models.Task{Title: "Task"})
+	}
+
+	var ids []int
+	err := repo.ExportTasks(ctx, func(task models.Task) error {
+		ids = append(ids, task.ID)
+		if len(ids) == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if len(ids) != 3 || ids[0] != 1 {
+		t.Errorf("expected tasks 1 to 3 before cancellation, got %v", ids)
+	}
+}
+
+func TestBatch_RollsBackOnError(t *testing.T) {
+	repo := NewRepository(store.NewStore())
+	ctx := context.Background()
+	task, _ := repo.CreateTask(ctx, models.Task{Title: "Keep"})
+
+	err := repo.Batch(ctx, func(tx *Tx) error {
+		if _, err := tx.CreateTask(models.Task{Title: "New"}); err != nil {
+			return err
+		}
+		if err := tx.DeleteTask(task.ID, nil); err != nil {
+			return err
+		}
+		return tx.DeleteTask(task.ID, nil)
+	})
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("expected the second delete to fail, got %v", err)
+	}
+	tasks, _ := repo.GetTasks(ctx, models.TaskQuery{})
+	if len(tasks) != 1 || tasks[0].ID != task.ID {
+		t.Errorf("expected the batch to be rolled back, got %+v", tasks)
+	}
+}
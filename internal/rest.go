@@ -2,71 +2,156 @@ package rest
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"task-manager/internal/config"
 	"task-manager/internal/handlers"
+	"task-manager/internal/models"
 	"task-manager/internal/repository"
 	svc "task-manager/internal/services"
 	"task-manager/internal/store"
+	_ "task-manager/internal/store/bptree"
 	"task-manager/pkg/logger"
 
 )
 
 type Rest struct {
-	config   *config.Config
-	handlers *handlers.Handlers
-	srv      *http.Server
-	store    *store.Store
-	service  *svc.TaskService
-	router   *http.ServeMux
+	config  *config.Config
+	srv     *http.Server
+	store   store.Driver
+	service *svc.TaskService
+	router  *http.ServeMux
 }
 
-func NewRest(cfg *config.Config) *Rest {
-	store := store.NewStore()
-	repository := repository.NewRepository(store)
-	taskService := svc.NewTaskService(repository)
-	taskHandlers := handlers.NewHandlers(taskService)
+func NewRest(cfg *config.Config) (*Rest, error) {
+	syncPolicy, err := store.ParseSyncPolicy(cfg.Store.WALSync)
+	if err != nil {
+		return nil, err
+	}
+	transitions := svc.DefaultTransitions
+	if len(cfg.TaskTransitions) > 0 {
+		transitions, err = svc.ParseTransitions(cfg.TaskTransitions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	limits := models.DefaultLimits
+	applyTextLimits(&limits.Title, cfg.Validation.Title)
+	applyTextLimits(&limits.Description, cfg.Validation.Description)
+	validator, err := models.NewValidator(limits)
+	if err != nil {
+		return nil, fmt.Errorf("validation: %w", err)
+	}
+	serverFields, err := handlers.ParseServerFields(cfg.ServerFields)
+	if err != nil {
+		return nil, err
+	}
+	handlerOpts := []handlers.Option{handlers.WithValidator(validator), handlers.WithServerFields(serverFields)}
+	if cfg.MaxBodyBytes > 0 {
+		handlerOpts = append(handlerOpts, handlers.WithMaxBodyBytes(cfg.MaxBodyBytes))
+	}
+	if cfg.CursorSecret != "" {
+		handlerOpts = append(handlerOpts, handlers.WithCursorKey([]byte(cfg.CursorSecret)))
+	}
+	versions := make(map[string]handlers.VersionPolicy, len(cfg.APIVersions))
+	for version, v := range cfg.APIVersions {
+		if version != "v1" && version != "v2" {
+			return nil, fmt.Errorf("api_versions: unknown version %q", version)
+		}
+		versions[version] = handlers.VersionPolicy{Deprecation: v.Deprecation, Sunset: v.Sunset, Link: v.Link}
+	}
+	var accessLog *handlers.AccessLog
+	if cfg.AccessLog.Enabled {
+		format, err := handlers.ParseAccessLogFormat(cfg.AccessLog.Format)
+		if err != nil {
+			return nil, err
+		}
+		accessLog = &handlers.AccessLog{Format: format, Exclude: cfg.AccessLog.Exclude}
+	}
+
+	// The store is opened last: the config is checked by now, so no error
+	// return leaves its files and goroutines behind.
+	store, err := store.Open(cfg.Store.Driver, &store.Config{
+		Dir:          cfg.Store.DataDir,
+		Sync:         syncPolicy,
+		SyncInterval: time.Duration(cfg.Store.WALSyncInterval),
+
+		SnapshotInterval: time.Duration(cfg.Store.SnapshotInterval),
+		SnapshotRetain:   cfg.Store.SnapshotRetain,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	repository := repository.NewRepository(store, repository.WithValidator(validator))
+	taskService := svc.NewTaskService(repository, svc.WithTransitions(transitions))
 
-	router := initRouter(taskHandlers)
+	router := initRouter(taskService, versions, handlerOpts...)
+	handler := handlers.RequestID(router)
+	if accessLog != nil {
+		handler = accessLog.Record(handler)
+	}
 
 	rest := &Rest{
-		config:   cfg,
-		handlers: taskHandlers,
-		router:   router,
+		config: cfg,
+		router: router,
 		srv: &http.Server{
 			Addr:    cfg.AppPort,
-			Handler: router,
+			Handler: handler,
 		},
 		store:   store,
 		service: taskService,
 	}
-	return rest
+	return rest, nil
 }
 
-func initRouter(h *handlers.Handlers) *http.ServeMux {
+// applyTextLimits overrides the limits set in cfg.
+func applyTextLimits(limits *models.TextLimits, cfg config.TextLimitsConfig) {
+	if cfg.MinLength != 0 {
+		limits.MinLength = cfg.MinLength
+	}
+	if cfg.MaxLength != 0 {
+		limits.MaxLength = cfg.MaxLength
+	}
+	if cfg.Pattern != "" {
+		limits.Pattern = cfg.Pattern
+	}
+}
+
+// initRouter mounts each API version under its prefix, with the policy
+// headers from versions. The unversioned paths are the v1 contract, kept so
+// existing clients work unchanged.
+func initRouter(taskService handlers.TaskService, versions map[string]handlers.VersionPolicy, opts ...handlers.Option) *http.ServeMux {
 	router := http.NewServeMux()
-	router.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			id := r.URL.Query().Get("id")
-			if id != "" {
-				h.GetTask(w, r)
-			} else {
-				h.GetTasks(w, r)
-			}
-		case http.MethodPost:
-			h.CreateTask(w, r)
-		case http.MethodDelete:
-			h.DeleteTask(w, r)
-		default:
-			w.Header().Set("Allow", "GET, POST, DELETE")
-			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
-		}
+
+	// Without a configured secret every mount shares one random cursor key,
+	// so a cursor issued under one path is accepted under the others. A key
+	// in opts comes later and wins.
+	cursorKey := make([]byte, 32)
+	rand.Read(cursorKey)
+	opts = append([]handlers.Option{handlers.WithCursorKey(cursorKey)}, opts...)
+
+	v1 := handlers.NewHandlers(taskService, append(opts, handlers.WithBasePath("/v1"))...)
+	router.Handle("/v1/", versions["v1"].Announce(http.StripPrefix("/v1", taskRoutes(v1, true))))
+
+	v2 := handlers.NewHandlers(taskService, append(opts, handlers.WithBasePath("/v2"), handlers.WithSerializer(handlers.V2))...)
+	router.Handle("/v2/", versions["v2"].Announce(http.StripPrefix("/v2", taskRoutes(v2, false))))
+
+	unversioned := versions["v1"].Announce(taskRoutes(handlers.NewHandlers(taskService, opts...), true))
+	router.Handle("/tasks", unversioned)
+	router.Handle("/tasks/", unversioned)
+
+	// Only the logger counters are served; the expvar page would expose the
+	// command line and memory statistics as well.
+	router.HandleFunc("GET /debug/logger", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(logger.Stats())
 	})
 
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	router.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("test task for LO"))
 	})
@@ -74,11 +159,45 @@ func initRouter(h *handlers.Handlers) *http.ServeMux {
 	return router
 }
 
+// taskRoutes registers the task endpoints of one API version. queryIDs adds
+// the deprecated ?id= forms, which only v1 has.
+func taskRoutes(h *handlers.Handlers, queryIDs bool) http.Handler {
+	router := http.NewServeMux()
+
+	router.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {
+		if queryIDs && r.URL.Query().Get("id") != "" {
+			h.Deprecated(h.GetTask)(w, r)
+			return
+		}
+		h.GetTasks(w, r)
+	})
+	router.HandleFunc("POST /tasks", h.CreateTask)
+	router.HandleFunc("GET /tasks/{id}", h.GetTask)
+	router.HandleFunc("PUT /tasks/{id}", h.UpdateTask)
+	router.HandleFunc("PATCH /tasks/{id}", h.PatchTask)
+	router.HandleFunc("DELETE /tasks/{id}", h.DeleteTask)
+	router.HandleFunc("GET /tasks/{id}/status", h.GetTaskStatus)
+	router.HandleFunc("PUT /tasks/{id}/status", h.SetTaskStatus)
+
+	if queryIDs {
+		// Deprecated: use /tasks/{id}.
+		router.HandleFunc("PUT /tasks", h.Deprecated(h.UpdateTask))
+		router.HandleFunc("PATCH /tasks", h.Deprecated(h.PatchTask))
+		router.HandleFunc("DELETE /tasks", h.Deprecated(h.DeleteTask))
+	}
+
+	router.HandleFunc("POST /tasks/batch", h.Batch)
+	router.HandleFunc("GET /tasks/export", h.ExportTasks)
+	router.HandleFunc("POST /tasks/import", h.ImportTasks)
+
+	return h.WithRoute(router)
+}
+
 func (r *Rest) RunRest() error {
-	logger.LogInfo("starting server on port " + r.config.AppPort)
+	logger.Info("starting server", "addr", r.config.AppPort)
 	err := r.srv.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
-		logger.LogError(fmt.Sprintf("failed to start server: %v", err))
+		logger.Error("failed to start server", "error", err)
 		return err
 	}
 
@@ -86,6 +205,13 @@ func (r *Rest) RunRest() error {
 }
 
 func (r *Rest) ShutdownRest(ctx context.Context) error {
-	logger.LogInfo("shutting down server")
-	return r.srv.Shutdown(ctx)
+	logger.Info("shutting down server")
+	err := r.srv.Shutdown(ctx)
+	if closeErr := r.store.Close(); closeErr != nil {
+		logger.Error("failed to close store", "error", closeErr)
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return err
 }
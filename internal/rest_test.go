@@ -0,0 +1,262 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"task-manager/internal/config"
+	"task-manager/internal/handlers"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	svc "task-manager/internal/services"
+	"task-manager/internal/store"
+)
+
+func newTestRouter(t *testing.T) *http.ServeMux {
+	t.Helper()
+	return newVersionedRouter(t, nil)
+}
+
+func newVersionedRouter(t *testing.T, versions map[string]handlers.VersionPolicy) *http.ServeMux {
+	t.Helper()
+	service := svc.NewTaskService(repository.NewRepository(store.NewStore()))
+	return initRouter(service, versions)
+}
+
+func serve(router http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if method == http.MethodPatch {
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPathRouting(t *testing.T) {
+	router := newTestRouter(t)
+
+	w := serve(router, http.MethodPost, "/tasks", `{"title":"Task"}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/tasks/1" {
+		t.Fatalf("expected 201 with Location /tasks/1, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = serve(router, http.MethodGet, "/tasks/1", "")
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "" {
+		t.Fatalf("expected 200 without Deprecation, got %d %q", w.Code, w.Header().Get("Deprecation"))
+	}
+
+	w = serve(router, http.MethodPut, "/tasks/1/status", `{"status":"in_progress"}`)
+	var status struct {
+		Status    models.Status `json:"status"`
+		StartedAt *string       `json:"started_at"`
+	}
+	json.NewDecoder(w.Body).Decode(&status)
+	if w.Code != http.StatusOK || status.Status != models.StatusInProgress || status.StartedAt == nil {
+		t.Fatalf("expected status to move to in_progress, got %d %+v", w.Code, status)
+	}
+
+	w = serve(router, http.MethodPatch, "/tasks/1", `{"description":"patched"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	w = serve(router, http.MethodGet, "/tasks/1/status", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"in_progress"`) {
+		t.Fatalf("expected the status sub-resource, got %d %s", w.Code, w.Body)
+	}
+
+	w = serve(router, http.MethodDelete, "/tasks/1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w = serve(router, http.MethodGet, "/tasks/1", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", w.Code)
+	}
+}
+
+func TestQueryIDAliasesAreDeprecated(t *testing.T) {
+	router := newTestRouter(t)
+	serve(router, http.MethodPost, "/tasks", `{"title":"Task"}`)
+
+	for _, tt := range []struct {
+		method, body string
+	}{
+		{http.MethodGet, ""},
+		{http.MethodPut, `{"title":"Renamed"}`},
+		{http.MethodPatch, `{"description":"patched"}`},
+		{http.MethodDelete, ""},
+	} {
+		w := serve(router, tt.method, "/tasks?id=1", tt.body)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", tt.method, w.Code)
+		}
+		if w.Header().Get("Deprecation") == "" {
+			t.Errorf("%s: expected a Deprecation header", tt.method)
+		}
+		if link := w.Header().Get("Link"); link != `</tasks/1>; rel="successor-version"` {
+			t.Errorf("%s: expected a successor link, got %q", tt.method, link)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	router := newTestRouter(t)
+
+	w := serve(router, http.MethodPost, "/tasks/1", "")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); !strings.Contains(allow, "PATCH") {
+		t.Errorf("expected Allow to list PATCH, got %q", allow)
+	}
+}
+
+func TestVersionedPaths(t *testing.T) {
+	router := newTestRouter(t)
+
+	w := serve(router, http.MethodPost, "/v1/tasks", `{"title":"Task"}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/v1/tasks/1" {
+		t.Fatalf("expected 201 with Location /v1/tasks/1, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	var task models.Task
+	json.NewDecoder(w.Body).Decode(&task)
+	if task.Title != "Task" {
+		t.Fatalf("expected a bare task from v1, got %+v", task)
+	}
+
+	w = serve(router, http.MethodGet, "/v2/tasks/1", "")
+	var got struct {
+		Data   models.Task     `json:"data"`
+		Meta   handlers.Meta   `json:"meta"`
+		Errors json.RawMessage `json:"errors"`
+	}
+	json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || got.Data.Title != "Task" || got.Meta.Version != "v2" || got.Errors != nil {
+		t.Fatalf("expected the task in a v2 envelope, got %d %+v", w.Code, got)
+	}
+
+	w = serve(router, http.MethodGet, "/v2/tasks?limit=1", "")
+	var page struct {
+		Meta handlers.Meta `json:"meta"`
+	}
+	json.NewDecoder(w.Body).Decode(&page)
+	if page.Meta.Page == nil || page.Meta.Page.Limit != 1 {
+		t.Fatalf("expected paging in meta, got %+v", page.Meta)
+	}
+
+	w = serve(router, http.MethodGet, "/v2/tasks/7", "")
+	var failed struct {
+		handlers.Problem
+		Data   json.RawMessage    `json:"data"`
+		Meta   handlers.Meta      `json:"meta"`
+		Errors []handlers.Problem `json:"errors"`
+	}
+	json.NewDecoder(w.Body).Decode(&failed)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != handlers.ProblemContentType {
+		t.Fatalf("expected a 404 problem, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if failed.Status != http.StatusNotFound || failed.Code != "task_not_found" || string(failed.Data) != "null" || failed.Meta.Version != "v2" {
+		t.Fatalf("expected the problem with data and meta members, got %+v", failed)
+	}
+	if len(failed.Errors) != 1 || failed.Errors[0].Code != "task_not_found" {
+		t.Fatalf("expected errors to list the problem, got %+v", failed.Errors)
+	}
+
+	w = serve(router, http.MethodPost, "/v2/tasks", `{}`)
+	json.NewDecoder(w.Body).Decode(&failed)
+	if w.Code != http.StatusBadRequest || len(failed.Errors) != 1 || len(failed.Errors[0].Errors) == 0 || failed.Meta.Version != "v2" {
+		t.Fatalf("expected the invalid fields inside the listed problem, got %d %+v", w.Code, failed)
+	}
+
+	if w = serve(router, http.MethodGet, "/v2/tasks?id=1", ""); w.Header().Get("Deprecation") != "" {
+		t.Errorf("expected v2 to have no ?id= alias")
+	}
+	if w = serve(router, http.MethodDelete, "/v2/tasks/1", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expected 204 from a v2 delete, got %d", w.Code)
+	}
+}
+
+func TestCursorsWorkAcrossMounts(t *testing.T) {
+	router := newTestRouter(t)
+	for _, title := range []string{"A", "B", "C"} {
+		serve(router, http.MethodPost, "/tasks", `{"title":"`+title+`"}`)
+	}
+
+	w := serve(router, http.MethodGet, "/tasks?limit=1", "")
+	link := w.Header().Get("Link")
+	_, token, ok := strings.Cut(link, "after=")
+	if !ok {
+		t.Fatalf("expected a next link, got %q", link)
+	}
+	token, _, _ = strings.Cut(token, ">")
+
+	for _, prefix := range []string{"/v1", "/v2"} {
+		if w = serve(router, http.MethodGet, prefix+"/tasks?limit=1&after="+token, ""); w.Code != http.StatusOK {
+			t.Errorf("expected %s to accept a cursor from /tasks, got %d %s", prefix, w.Code, w.Body)
+		}
+	}
+}
+
+func TestVersionSunsetHeaders(t *testing.T) {
+	sunset := time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
+	router := newVersionedRouter(t, map[string]handlers.VersionPolicy{
+		"v1": {Deprecation: time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC), Sunset: sunset, Link: "https://example.com/v2"},
+	})
+
+	for _, target := range []string{"/v1/tasks", "/tasks"} {
+		w := serve(router, http.MethodGet, target, "")
+		if got := w.Header().Get("Sunset"); got != "Wed, 30 Jun 2027 00:00:00 GMT" {
+			t.Errorf("%s: expected a Sunset header, got %q", target, got)
+		}
+		if w.Header().Get("Deprecation") == "" {
+			t.Errorf("%s: expected a Deprecation header", target)
+		}
+		if links := strings.Join(w.Header().Values("Link"), ", "); !strings.Contains(links, `<https://example.com/v2>; rel="sunset"`) {
+			t.Errorf("%s: expected a sunset link, got %q", target, links)
+		}
+	}
+
+	w := serve(router, http.MethodGet, "/v2/tasks", "")
+	if w.Header().Get("Sunset") != "" || w.Header().Get("Deprecation") != "" {
+		t.Errorf("expected v2 to carry no sunset headers, got %v", w.Header())
+	}
+}
+
+func TestDebugEndpoints(t *testing.T) {
+	router := newTestRouter(t)
+
+	w := serve(router, http.MethodGet, "/debug/logger", "")
+	var stats map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected the logger counters, got %d %v", w.Code, err)
+	}
+	if _, ok := stats["dropped"]; !ok {
+		t.Errorf("expected a dropped counter, got %v", stats)
+	}
+	if w = serve(router, http.MethodGet, "/debug/vars", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected /debug/vars not to be served, got %d", w.Code)
+	}
+}
+
+func TestNewRestChecksConfigBeforeOpeningStore(t *testing.T) {
+	dir := t.TempDir()
+	for name, cfg := range map[string]*config.Config{
+		"server fields": {ServerFields: "bogus"},
+		"api versions":  {APIVersions: map[string]config.APIVersionConfig{"v3": {}}},
+		"access log":    {AccessLog: config.AccessLogConfig{Enabled: true, Format: "apache"}},
+	} {
+		cfg.Store.DataDir = dir
+		if _, err := NewRest(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected the store not to be opened, found %v", entries)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"task-manager/internal/apperr"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"task-manager/pkg/logger"
+)
+
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
+
+// BatchItem is one operation of a batch. Update replaces the task like
+// UpdateTask; Precondition, if set, guards an update or delete.
+type BatchItem struct {
+	Op           BatchOp
+	ID           int
+	Task         models.Task
+	Precondition func(models.Task) error
+}
+
+// BatchResult is the outcome of one BatchItem. Task is the created or updated
+// task.
+type BatchResult struct {
+	Task models.Task
+	Err  error
+}
+
+// ErrBatchAborted is the result of every other item when an atomic batch
+// fails.
+var ErrBatchAborted = apperr.New(apperr.Conflict, "batch_aborted", "not applied: another operation in the batch failed")
+
+// BatchError reports the item that aborted an atomic batch.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Batch applies items in order inside one storage transaction. When atomic
+// is set, the first failing item rolls back the whole batch and is returned
+// as a *BatchError. Otherwise failing items are skipped and the rest are
+// committed. Either way the results line up with items.
+func (t *TaskService) Batch(ctx context.Context, items []BatchItem, atomic bool) ([]BatchResult, error) {
+	results := make([]BatchResult, len(items))
+	err := t.rep.Batch(ctx, func(tx *repository.Tx) error {
+		for i, item := range items {
+			results[i].Task, results[i].Err = t.applyBatchItem(ctx, tx, item)
+			if results[i].Err != nil && atomic {
+				return &BatchError{Index: i, Err: results[i].Err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) {
+			return nil, err
+		}
+		for i := range results {
+			if i != batchErr.Index {
+				results[i] = BatchResult{Err: ErrBatchAborted}
+			}
+		}
+		logger.InfoContext(ctx, "batch aborted", "operations", len(items), "index", batchErr.Index, "error", batchErr.Err)
+		return results, err
+	}
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	logger.InfoContext(ctx, "batch applied", "operations", len(items), "failed", failed, "atomic", atomic)
+	return results, nil
+}
+
+func (t *TaskService) applyBatchItem(ctx context.Context, tx *repository.Tx, item BatchItem) (models.Task, error) {
+	var task models.Task
+	var err error
+	switch item.Op {
+	case BatchCreate:
+		task, err = tx.CreateTask(t.prepareCreate(item.Task))
+	case BatchUpdate:
+		task, err = tx.UpdateTask(item.ID, t.guardUpdate(ctx, func(current models.Task) (models.Task, error) {
+			if item.Precondition != nil {
+				if err := item.Precondition(current); err != nil {
+					return models.Task{}, err
+				}
+			}
+			return item.Task, nil
+		}))
+	case BatchDelete:
+		err = tx.DeleteTask(item.ID, item.Precondition)
+	default:
+		err = apperr.Invalid(models.NewValidationError("/op", "unknown operation %q", item.Op))
+	}
+	if errors.Is(err, repository.ErrTaskNotFound) {
+		err = ErrTaskNotFound
+	}
+	return task, err
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"task-manager/internal/store"
+)
+
+func newBatchService(t *testing.T) (*TaskService, *repository.Repository) {
+	t.Helper()
+	repo := repository.NewRepository(store.NewStore())
+	ctx := context.Background()
+	for _, title := range []string{"First", "Second"} {
+		if _, err := repo.CreateTask(ctx, models.Task{Title: title, Status: models.StatusTodo}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return NewTaskService(repo), repo
+}
+
+func TestBatch_Atomic(t *testing.T) {
+	service, repo := newBatchService(t)
+	ctx := context.Background()
+
+	results, err := service.Batch(ctx, []BatchItem{
+		{Op: BatchCreate, Task: models.Task{Title: "Third"}},
+		{Op: BatchUpdate, ID: 1, Task: models.Task{Title: "First, edited", Status: models.StatusInProgress}},
+		{Op: BatchDelete, ID: 2},
+	}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Task.ID != 3 || results[0].Task.Status != models.StatusTodo {
+		t.Errorf("expected created task 3 in todo, got %+v", results[0].Task)
+	}
+	if results[1].Task.StartedAt == nil {
+		t.Errorf("expected the update to go through the status rules")
+	}
+	tasks, _ := repo.GetTasks(ctx, models.TaskQuery{})
+	if len(tasks) != 2 || tasks[0].Title != "First, edited" || tasks[1].ID != 3 {
+		t.Errorf("unexpected tasks after batch: %+v", tasks)
+	}
+}
+
+func TestBatch_AtomicRollsBack(t *testing.T) {
+	service, repo := newBatchService(t)
+	ctx := context.Background()
+
+	results, err := service.Batch(ctx, []BatchItem{
+		{Op: BatchCreate, Task: models.Task{Title: "Third"}},
+		{Op: BatchDelete, ID: 1},
+		{Op: BatchUpdate, ID: 42, Task: models.Task{Title: "Missing"}},
+	}, true)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 2 || !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("expected item 2 to fail with not found, got %v", err)
+	}
+	if !errors.Is(results[0].Err, ErrBatchAborted) || !errors.Is(results[2].Err, ErrTaskNotFound) {
+		t.Errorf("unexpected results %+v", results)
+	}
+	tasks, _ := repo.GetTasks(ctx, models.TaskQuery{})
+	if len(tasks) != 2 || tasks[0].ID != 1 {
+		t.Errorf("expected the batch to be rolled back, got %+v", tasks)
+	}
+}
+
+func TestBatch_BestEffort(t *testing.T) {
+	service, repo := newBatchService(t)
+	ctx := context.Background()
+
+	results, err := service.Batch(ctx, []BatchItem{
+		{Op: BatchCreate, Task: models.Task{Title: ""}},
+		{Op: BatchUpdate, ID: 1, Task: models.Task{Title: "First", Status: models.StatusDone}},
+		{Op: BatchUpdate, ID: 2, Task: models.Task{Title: "Done"}, Precondition: func(models.Task) error {
+			return errors.New("stale")
+		}},
+		{Op: "archive", ID: 2},
+		{Op: BatchDelete, ID: 2},
+	}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var invalid *models.ValidationError
+	if !errors.As(results[0].Err, &invalid) || !errors.As(results[3].Err, &invalid) {
+		t.Errorf("expected validation errors, got %v and %v", results[0].Err, results[3].Err)
+	}
+	if results[1].Err != nil || results[4].Err != nil || results[2].Err == nil {
+		t.Errorf("unexpected results %+v", results)
+	}
+	tasks, _ := repo.GetTasks(ctx, models.TaskQuery{})
+	if len(tasks) != 1 || tasks[0].Status != models.StatusDone {
+		t.Errorf("expected only task 1 to remain, done; got %+v", tasks)
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+
+	"task-manager/internal/models"
+)
+
+// Transitions lists, for every status, the statuses a task may move to.
+type Transitions map[models.Status][]models.Status
+
+var DefaultTransitions = Transitions{
+	models.StatusTodo:       {models.StatusInProgress, models.StatusBlocked, models.StatusDone, models.StatusCancelled},
+	models.StatusInProgress: {models.StatusTodo, models.StatusBlocked, models.StatusDone, models.StatusCancelled},
+	models.StatusBlocked:    {models.StatusTodo, models.StatusInProgress, models.StatusCancelled},
+	models.StatusDone:       {models.StatusTodo, models.StatusInProgress},
+	models.StatusCancelled:  {models.StatusTodo},
+}
+
+// ParseTransitions builds a transition table from configuration, rejecting
+// statuses that do not exist.
+func ParseTransitions(raw map[string][]string) (Transitions, error) {
+	transitions := make(Transitions, len(raw))
+	for from, targets := range raw {
+		if !models.Status(from).Valid() {
+			return nil, fmt.Errorf("transitions: unknown status %q", from)
+		}
+		for _, to := range targets {
+			if !models.Status(to).Valid() {
+				return nil, fmt.Errorf("transitions: unknown status %q in targets of %q", to, from)
+			}
+			transitions[models.Status(from)] = append(transitions[models.Status(from)], models.Status(to))
+		}
+	}
+	return transitions, nil
+}
+
+func (t Transitions) Allowed(from, to models.Status) bool {
+	return from == to || slices.Contains(t[from], to)
+}
+
+// TransitionError reports a status change the transition table forbids.
+type TransitionError struct {
+	From models.Status
+	To   models.Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change status from %q to %q", e.From, e.To)
+}
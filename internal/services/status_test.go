@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"task-manager/internal/apperr"
+	"task-manager/internal/models"
+)
+
+// statefulRepo keeps a single task and applies updates to it the way the
+// repository does.
+func statefulRepo(task *models.Task) *MockTaskRepository {
+	return &MockTaskRepository{
+		CreateTaskFunc: func(ctx context.Context, created models.Task) (models.Task, error) {
+			created.ID = 1
+			*task = created
+			return created, nil
+		},
+		UpdateTaskFunc: func(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error) {
+			updated, err := update(*task)
+			if err != nil {
+				return models.Task{}, err
+			}
+			*task = updated
+			return updated, nil
+		},
+	}
+}
+
+func setStatus(status models.Status) func(models.Task) (models.Task, error) {
+	return func(task models.Task) (models.Task, error) {
+		task.Status = status
+		return task, nil
+	}
+}
+
+func TestCreateTask_DefaultsToTodo(t *testing.T) {
+	var stored models.Task
+	service := NewTaskService(statefulRepo(&stored))
+
+	created, err := service.CreateTask(context.Background(), models.Task{Title: "Task"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Status != models.StatusTodo {
+		t.Errorf("expected status %q, got %q", models.StatusTodo, created.Status)
+	}
+}
+
+func TestUpdateTask_IllegalTransition(t *testing.T) {
+	stored := models.Task{ID: 1, Title: "Task", Status: models.StatusCancelled}
+	service := NewTaskService(statefulRepo(&stored))
+
+	_, err := service.UpdateTask(context.Background(), 1, setStatus(models.StatusDone))
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("expected TransitionError, got %v", err)
+	}
+	if transitionErr.From != models.StatusCancelled || transitionErr.To != models.StatusDone {
+		t.Errorf("unexpected transition in error: %+v", transitionErr)
+	}
+	if stored.Status != models.StatusCancelled {
+		t.Errorf("expected task to be untouched, got %q", stored.Status)
+	}
+}
+
+func TestUpdateTask_UnknownStatus(t *testing.T) {
+	stored := models.Task{ID: 1, Title: "Task", Status: models.StatusTodo}
+	service := NewTaskService(statefulRepo(&stored))
+
+	_, err := service.UpdateTask(context.Background(), 1, setStatus("bogus"))
+	if e := apperr.From(err); e.Kind != apperr.Validation {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	var transitionErr *TransitionError
+	if errors.As(err, &transitionErr) {
+		t.Errorf("expected no transition error, got %v", err)
+	}
+}
+
+func TestUpdateTask_CustomTransitions(t *testing.T) {
+	stored := models.Task{ID: 1, Title: "Task", Status: models.StatusTodo}
+	transitions, err := ParseTransitions(map[string][]string{"todo": {"done"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	service := NewTaskService(statefulRepo(&stored), WithTransitions(transitions))
+
+	if _, err := service.UpdateTask(context.Background(), 1, setStatus(models.StatusInProgress)); err == nil {
+		t.Errorf("expected todo -> in_progress to be rejected")
+	}
+	if _, err := service.UpdateTask(context.Background(), 1, setStatus(models.StatusDone)); err != nil {
+		t.Errorf("expected todo -> done to be allowed, got %v", err)
+	}
+}
+
+func TestParseTransitions_UnknownStatus(t *testing.T) {
+	if _, err := ParseTransitions(map[string][]string{"todo": {"archived"}}); err == nil {
+		t.Errorf("expected error for unknown status")
+	}
+}
+
+func TestUpdateTask_LifecycleTimestamps(t *testing.T) {
+	stored := models.Task{ID: 1, Title: "Task", Status: models.StatusTodo}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	service := NewTaskService(statefulRepo(&stored), WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	task, _ := service.UpdateTask(ctx, 1, setStatus(models.StatusInProgress))
+	if task.StartedAt == nil || !task.StartedAt.Equal(now) {
+		t.Fatalf("expected StartedAt %v, got %v", now, task.StartedAt)
+	}
+
+	started := now
+	now = now.Add(time.Hour)
+	task, _ = service.UpdateTask(ctx, 1, setStatus(models.StatusDone))
+	if task.CompletedAt == nil || !task.CompletedAt.Equal(now) {
+		t.Fatalf("expected CompletedAt %v, got %v", now, task.CompletedAt)
+	}
+	if !task.StartedAt.Equal(started) {
+		t.Errorf("expected StartedAt to be kept, got %v", task.StartedAt)
+	}
+
+	task, _ = service.UpdateTask(ctx, 1, func(task models.Task) (models.Task, error) {
+		task.Title = "Renamed"
+		task.Status = ""
+		task.CompletedAt = nil
+		return task, nil
+	})
+	if task.Status != models.StatusDone || task.CompletedAt == nil {
+		t.Errorf("expected status and CompletedAt to be kept, got %q %v", task.Status, task.CompletedAt)
+	}
+
+	task, _ = service.UpdateTask(ctx, 1, setStatus(models.StatusTodo))
+	if task.CompletedAt != nil {
+		t.Errorf("expected CompletedAt to be cleared on reopen, got %v", task.CompletedAt)
+	}
+}
@@ -3,9 +3,12 @@ package services
 import (
 	"context"
 	"errors"
+	"time"
 
+	"task-manager/internal/apperr"
 	"task-manager/internal/models"
 	"task-manager/internal/repository"
+	"task-manager/pkg/logger"
 
 )
 
@@ -16,22 +19,47 @@ var (
 type TaskRepository interface {
 	CreateTask(ctx context.Context, task models.Task) (models.Task, error)
 	GetTask(ctx context.Context, id int) (models.Task, error)
-	GetTasks(ctx context.Context) ([]models.Task, error)
-	DeleteTask(ctx context.Context, id int) error
+	GetTasks(ctx context.Context, query models.TaskQuery) ([]models.Task, error)
+	ExportTasks(ctx context.Context, fn func(models.Task) error) error
+	UpdateTask(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error)
+	DeleteTask(ctx context.Context, id int, precondition func(models.Task) error) error
+	Batch(ctx context.Context, fn func(tx *repository.Tx) error) error
 }
 
 type TaskService struct {
-	rep TaskRepository
+	rep         TaskRepository
+	transitions Transitions
+	now         func() time.Time
 }
 
-func NewTaskService(repository TaskRepository) *TaskService {
-	return &TaskService{
-		rep: repository,
+type Option func(*TaskService)
+
+func WithTransitions(transitions Transitions) Option {
+	return func(t *TaskService) {
+		t.transitions = transitions
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(t *TaskService) {
+		t.now = now
+	}
+}
+
+func NewTaskService(repository TaskRepository, opts ...Option) *TaskService {
+	t := &TaskService{
+		rep:         repository,
+		transitions: DefaultTransitions,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(t)
 	}
+	return t
 }
 
 func (t *TaskService) CreateTask(ctx context.Context, task models.Task) (models.Task, error) {
-	createdTask, err := t.rep.CreateTask(ctx, task)
+	createdTask, err := t.rep.CreateTask(ctx, t.prepareCreate(task))
 	if err != nil {
 		return models.Task{}, err
 	}
@@ -50,13 +78,32 @@ func (t *TaskService) GetTask(ctx context.Context, id int) (models.Task, error)
 	return task, nil
 }
 
-func (t *TaskService) GetTasks(ctx context.Context) ([]models.Task, error) {
-	return t.rep.GetTasks(ctx)
+func (t *TaskService) GetTasks(ctx context.Context, query models.TaskQuery) ([]models.Task, error) {
+	return t.rep.GetTasks(ctx, query)
 }
 
-func (t *TaskService) DeleteTask(ctx context.Context, id int) error {
+func (t *TaskService) ExportTasks(ctx context.Context, fn func(models.Task) error) error {
+	return t.rep.ExportTasks(ctx, fn)
+}
 
-	err := t.rep.DeleteTask(ctx, id)
+// UpdateTask applies update and enforces the status transition table. An
+// empty status in the result keeps the current one; StartedAt and
+// CompletedAt are maintained by the service and cannot be set by update.
+func (t *TaskService) UpdateTask(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error) {
+	task, err := t.rep.UpdateTask(ctx, id, t.guardUpdate(ctx, update))
+	if err != nil {
+		if errors.Is(err, repository.ErrTaskNotFound) {
+			return models.Task{}, ErrTaskNotFound
+		}
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+func (t *TaskService) DeleteTask(ctx context.Context, id int, precondition func(models.Task) error) error {
+
+	err := t.rep.DeleteTask(ctx, id, precondition)
 	if err != nil {
 		if errors.Is(err, repository.ErrTaskNotFound) {
 			return ErrTaskNotFound
@@ -66,3 +113,59 @@ func (t *TaskService) DeleteTask(ctx context.Context, id int) error {
 
 	return nil
 }
+
+// prepareCreate applies the defaults of a new task.
+func (t *TaskService) prepareCreate(task models.Task) models.Task {
+	if task.Status == "" {
+		task.Status = models.StatusTodo
+	}
+	task.StartedAt, task.CompletedAt = nil, nil
+	t.stamp(&task, "")
+	return task
+}
+
+// guardUpdate wraps update with the status transition rules.
+func (t *TaskService) guardUpdate(ctx context.Context, update func(models.Task) (models.Task, error)) func(models.Task) (models.Task, error) {
+	return func(current models.Task) (models.Task, error) {
+		next, err := update(current)
+		if err != nil {
+			return models.Task{}, err
+		}
+		if current.Status == "" {
+			current.Status = models.StatusTodo
+		}
+		if next.Status == "" {
+			next.Status = current.Status
+		}
+		// An unknown status is a bad request, not a transition to refuse.
+		if !next.Status.Valid() {
+			return models.Task{}, apperr.Invalid(models.NewValidationError("/status", "unknown status %q", next.Status))
+		}
+		if !t.transitions.Allowed(current.Status, next.Status) {
+			logger.DebugContext(ctx, "status transition rejected", "task_id", current.ID, "from", current.Status, "to", next.Status)
+			return models.Task{}, apperr.Wrap(&TransitionError{From: current.Status, To: next.Status}, apperr.Conflict, "invalid_transition")
+		}
+		next.StartedAt, next.CompletedAt = current.StartedAt, current.CompletedAt
+		t.stamp(&next, current.Status)
+		return next, nil
+	}
+}
+
+// stamp records lifecycle timestamps when task enters a new status.
+func (t *TaskService) stamp(task *models.Task, from models.Status) {
+	if task.Status == from {
+		return
+	}
+	now := t.now()
+	switch task.Status {
+	case models.StatusInProgress:
+		if task.StartedAt == nil {
+			task.StartedAt = &now
+		}
+	case models.StatusDone:
+		task.CompletedAt = &now
+	}
+	if from == models.StatusDone && task.Status != models.StatusDone {
+		task.CompletedAt = nil
+	}
+}
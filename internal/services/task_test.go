@@ -11,10 +11,13 @@ import (
 )
 
 type MockTaskRepository struct {
-	CreateTaskFunc func(ctx context.Context, task models.Task) (models.Task, error)
-	GetTaskFunc    func(ctx context.Context, id int) (models.Task, error)
-	GetTasksFunc   func(ctx context.Context) ([]models.Task, error)
-	DeleteTaskFunc func(ctx context.Context, id int) error
+	CreateTaskFunc  func(ctx context.Context, task models.Task) (models.Task, error)
+	GetTaskFunc     func(ctx context.Context, id int) (models.Task, error)
+	GetTasksFunc    func(ctx context.Context, query models.TaskQuery) ([]models.Task, error)
+	ExportTasksFunc func(ctx context.Context, fn func(models.Task) error) error
+	UpdateTaskFunc  func(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error)
+	DeleteTaskFunc  func(ctx context.Context, id int) error
+	BatchFunc       func(ctx context.Context, fn func(tx *repository.Tx) error) error
 }
 
 func (m *MockTaskRepository) CreateTask(ctx context.Context, task models.Task) (models.Task, error) {
@@ -23,12 +26,21 @@ func (m *MockTaskRepository) CreateTask(ctx context.Context, task models.Task) (
 func (m *MockTaskRepository) GetTask(ctx context.Context, id int) (models.Task, error) {
 	return m.GetTaskFunc(ctx, id)
 }
-func (m *MockTaskRepository) GetTasks(ctx context.Context) ([]models.Task, error) {
-	return m.GetTasksFunc(ctx)
+func (m *MockTaskRepository) GetTasks(ctx context.Context, query models.TaskQuery) ([]models.Task, error) {
+	return m.GetTasksFunc(ctx, query)
 }
-func (m *MockTaskRepository) DeleteTask(ctx context.Context, id int) error {
+func (m *MockTaskRepository) ExportTasks(ctx context.Context, fn func(models.Task) error) error {
+	return m.ExportTasksFunc(ctx, fn)
+}
+func (m *MockTaskRepository) UpdateTask(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error) {
+	return m.UpdateTaskFunc(ctx, id, update)
+}
+func (m *MockTaskRepository) DeleteTask(ctx context.Context, id int, precondition func(models.Task) error) error {
 	return m.DeleteTaskFunc(ctx, id)
 }
+func (m *MockTaskRepository) Batch(ctx context.Context, fn func(tx *repository.Tx) error) error {
+	return m.BatchFunc(ctx, fn)
+}
 
 func TestCreateTask_Success(t *testing.T) {
 	mockRepo := &MockTaskRepository{
@@ -51,13 +63,13 @@ func TestCreateTask_Success(t *testing.T) {
 
 func TestGetTasks_Success(t *testing.T) {
 	mockRepo := &MockTaskRepository{
-		GetTasksFunc: func(ctx context.Context) ([]models.Task, error) {
+		GetTasksFunc: func(ctx context.Context, query models.TaskQuery) ([]models.Task, error) {
 			return []models.Task{{ID: 1, Title: "Task1"}}, nil
 		},
 	}
 	service := NewTaskService(mockRepo)
 
-	tasks, err := service.GetTasks(context.Background())
+	tasks, err := service.GetTasks(context.Background(), models.TaskQuery{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -74,7 +86,7 @@ func TestDeleteTask_Success(t *testing.T) {
 	}
 	service := NewTaskService(mockRepo)
 
-	if err := service.DeleteTask(context.Background(), 1); err != nil {
+	if err := service.DeleteTask(context.Background(), 1, nil); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
@@ -87,7 +99,23 @@ func TestDeleteTask_NotFound(t *testing.T) {
 	}
 	service := NewTaskService(mockRepo)
 
-	err := service.DeleteTask(context.Background(), 42)
+	err := service.DeleteTask(context.Background(), 42, nil)
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestUpdateTask_NotFound(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		UpdateTaskFunc: func(ctx context.Context, id int, update func(models.Task) (models.Task, error)) (models.Task, error) {
+			return models.Task{}, repository.ErrTaskNotFound
+		},
+	}
+	service := NewTaskService(mockRepo)
+
+	_, err := service.UpdateTask(context.Background(), 42, func(task models.Task) (models.Task, error) {
+		return task, nil
+	})
 	if !errors.Is(err, ErrTaskNotFound) {
 		t.Errorf("expected ErrTaskNotFound, got %v", err)
 	}
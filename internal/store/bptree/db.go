@@ -0,0 +1,359 @@
+// Package bptree is a single-file, page-based B+tree storage driver. Pages
+// are copy-on-write: a commit writes new pages for every modified node and
+// then switches the root in one of two alternating meta pages, so readers
+// keep a consistent view of the version they started on.
+package bptree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"task-manager/internal/models"
+	"task-manager/internal/store"
+)
+
+const (
+	DriverName = "bptree"
+	FileName   = "tasks.db"
+)
+
+var ErrClosed = errors.New("bptree: database is closed")
+
+func init() {
+	store.Register(DriverName, func(cfg *store.Config) (store.Driver, error) {
+		if cfg == nil || cfg.Dir == "" {
+			return nil, errors.New("bptree: a data directory is required")
+		}
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			return nil, err
+		}
+		return Open(filepath.Join(cfg.Dir, FileName), cfg.Sync != store.SyncNever)
+	})
+}
+
+type DB struct {
+	file *os.File
+	sync bool
+
+	// writeMu serialises write transactions.
+	writeMu sync.Mutex
+
+	// mu guards the fields below.
+	mu     sync.Mutex
+	meta   meta
+	closed bool
+	// free pages may be reused now; pending pages were freed by the
+	// transaction with the given txid and are reused once no reader can
+	// still see them.
+	free    []pgid
+	pending map[uint64][]pgid
+	readers map[uint64]int
+}
+
+var _ store.Driver = (*DB)(nil)
+
+func Open(path string, sync bool) (*DB, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{
+		file:    file,
+		sync:    sync,
+		pending: make(map[uint64][]pgid),
+		readers: make(map[uint64]int),
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size() == 0 {
+		err = db.init()
+	} else {
+		err = db.load()
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *DB) init() error {
+	m := meta{high: 2, nextID: 1}
+	for txid := uint64(0); txid < 2; txid++ {
+		m.txid = txid
+		if _, err := db.file.WriteAt(m.encode(), int64(txid)*pageSize); err != nil {
+			return err
+		}
+	}
+	db.meta = m
+	return db.file.Sync()
+}
+
+func (db *DB) load() error {
+	var best *meta
+	for i := int64(0); i < 2; i++ {
+		buf := make([]byte, pageSize)
+		if _, err := db.file.ReadAt(buf, i*pageSize); err != nil && err != io.EOF {
+			return err
+		}
+		m, err := decodeMeta(buf)
+		if err != nil {
+			continue
+		}
+		if best == nil || m.txid > best.txid {
+			best = &m
+		}
+	}
+	if best == nil {
+		return errInvalidMeta
+	}
+	db.meta = *best
+	if db.meta.freelist != 0 {
+		buf, err := db.readPages(db.meta.freelist)
+		if err != nil {
+			return err
+		}
+		ids, _, err := decodeFreelist(buf)
+		if err != nil {
+			return err
+		}
+		db.free = ids
+	}
+	return nil
+}
+
+// readPages reads the page at id along with its overflow pages.
+func (db *DB) readPages(id pgid) ([]byte, error) {
+	buf := make([]byte, pageSize)
+	if _, err := db.file.ReadAt(buf, int64(id)*pageSize); err != nil {
+		return nil, fmt.Errorf("bptree: read page %d: %w", id, err)
+	}
+	overflow := readPageHeader(buf).overflow
+	if overflow == 0 {
+		return buf, nil
+	}
+	full := make([]byte, (int(overflow)+1)*pageSize)
+	copy(full, buf)
+	if _, err := db.file.ReadAt(full[pageSize:], int64(id+1)*pageSize); err != nil {
+		return nil, fmt.Errorf("bptree: read page %d: %w", id, err)
+	}
+	return full, nil
+}
+
+func (db *DB) readNode(id int64) (*node, error) {
+	buf, err := db.readPages(pgid(id))
+	if err != nil {
+		return nil, err
+	}
+	return decodeNode(buf)
+}
+
+// beginRead pins the current version until the returned func is called.
+func (db *DB) beginRead() (meta, func(), error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return meta{}, nil, ErrClosed
+	}
+	m := db.meta
+	db.readers[m.txid]++
+	return m, func() {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		if db.readers[m.txid]--; db.readers[m.txid] == 0 {
+			delete(db.readers, m.txid)
+		}
+	}, nil
+}
+
+// releasePending moves pages freed by old transactions to the free list once
+// every open reader started on a later version. Callers hold db.mu.
+func (db *DB) releasePending() {
+	oldest := db.meta.txid
+	for txid := range db.readers {
+		oldest = min(oldest, txid)
+	}
+	for txid, ids := range db.pending {
+		if txid <= oldest {
+			db.free = append(db.free, ids...)
+			delete(db.pending, txid)
+		}
+	}
+	slices.Sort(db.free)
+}
+
+func (db *DB) Get(key int) (models.Task, bool, error) {
+	var task models.Task
+	var ok bool
+	err := db.View(func(tx store.ReadTx) error {
+		var err error
+		task, ok, err = tx.Get(key)
+		return err
+	})
+	return task, ok, err
+}
+
+func (db *DB) Scan(after int, fn func(models.Task) bool) error {
+	return db.View(func(tx store.ReadTx) error {
+		return tx.Scan(after, fn)
+	})
+}
+
+// View pins the current version for the duration of fn; commits made
+// meanwhile are not visible to it and do not reuse its pages.
+func (db *DB) View(fn func(tx store.ReadTx) error) error {
+	m, done, err := db.beginRead()
+	if err != nil {
+		return err
+	}
+	defer done()
+	return fn(&readTx{db: db, meta: m})
+}
+
+type readTx struct {
+	db   *DB
+	meta meta
+}
+
+func (tx *readTx) Get(key int) (models.Task, bool, error) {
+	return get(tx.db.readNode, int64(tx.meta.root), int64(key))
+}
+
+func (tx *readTx) Scan(after int, fn func(models.Task) bool) error {
+	if tx.meta.root == 0 {
+		return nil
+	}
+	_, err := scan(tx.db.readNode, int64(tx.meta.root), int64(after), fn)
+	return err
+}
+
+func (db *DB) Set(key int, value models.Task) error {
+	return db.Update(func(tx store.Tx) error {
+		return tx.Set(key, value)
+	})
+}
+
+func (db *DB) Delete(key int) (bool, error) {
+	var deleted bool
+	err := db.Update(func(tx store.Tx) error {
+		var err error
+		deleted, err = tx.Delete(key)
+		return err
+	})
+	return deleted, err
+}
+
+func (db *DB) NextID() (int, error) {
+	var id int
+	err := db.Update(func(tx store.Tx) error {
+		var err error
+		id, err = tx.NextID()
+		return err
+	})
+	return id, err
+}
+
+func (db *DB) Update(fn func(tx store.Tx) error) error {
+	db.writeMu.Lock()
+	defer db.writeMu.Unlock()
+
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return ErrClosed
+	}
+	db.releasePending()
+	tx := &writeTx{
+		db:    db,
+		meta:  db.meta,
+		free:  slices.Clone(db.free),
+		nodes: make(map[int64]*node),
+	}
+	db.mu.Unlock()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if !tx.changed {
+		return nil
+	}
+	return tx.commit()
+}
+
+func (db *DB) Close() error {
+	db.writeMu.Lock()
+	defer db.writeMu.Unlock()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return nil
+	}
+	db.closed = true
+	return db.file.Close()
+}
+
+func get(load func(int64) (*node, error), root, key int64) (models.Task, bool, error) {
+	if root == 0 {
+		return models.Task{}, false, nil
+	}
+	n, err := load(root)
+	if err != nil {
+		return models.Task{}, false, err
+	}
+	for !n.leaf {
+		i, _ := n.index(key)
+		if n, err = load(n.children[i]); err != nil {
+			return models.Task{}, false, err
+		}
+	}
+	i, found := n.index(key)
+	if !found {
+		return models.Task{}, false, nil
+	}
+	var task models.Task
+	if err := json.Unmarshal(n.values[i], &task); err != nil {
+		return models.Task{}, false, err
+	}
+	return task, true, nil
+}
+
+// scan walks the subtree at id in key order, visiting keys greater than
+// after. It reports false once fn asks to stop.
+func scan(load func(int64) (*node, error), id, after int64, fn func(models.Task) bool) (bool, error) {
+	n, err := load(id)
+	if err != nil {
+		return false, err
+	}
+	if n.leaf {
+		for i, key := range n.keys {
+			if key <= after {
+				continue
+			}
+			var task models.Task
+			if err := json.Unmarshal(n.values[i], &task); err != nil {
+				return false, err
+			}
+			if !fn(task) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	start, _ := n.index(after)
+	for _, child := range n.children[start:] {
+		cont, err := scan(load, child, after, fn)
+		if err != nil || !cont {
+			return false, err
+		}
+	}
+	return true, nil
+}
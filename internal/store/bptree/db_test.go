@@ -0,0 +1,433 @@
+package bptree
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"task-manager/internal/models"
+	"task-manager/internal/store"
+)
+
+func openTestDB(t *testing.T) (*DB, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), FileName)
+	db, err := Open(path, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return db, path
+}
+
+func collect(t *testing.T, db *DB, after int) []int {
+	t.Helper()
+	var ids []int
+	err := db.Scan(after, func(task models.Task) bool {
+		ids = append(ids, task.ID)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return ids
+}
+
+func TestSetGetDelete(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+
+	if err := db.Set(1, models.Task{ID: 1, Title: "Task 1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, ok, err := db.Get(1)
+	if err != nil || !ok || got.Title != "Task 1" {
+		t.Fatalf("expected task 1, got %v %v %v", got, ok, err)
+	}
+	deleted, err := db.Delete(1)
+	if err != nil || !deleted {
+		t.Fatalf("expected task to be deleted, got %v %v", deleted, err)
+	}
+	if _, ok, _ := db.Get(1); ok {
+		t.Errorf("expected task to be gone")
+	}
+	if deleted, _ := db.Delete(1); deleted {
+		t.Errorf("expected second delete to report false")
+	}
+}
+
+func TestRandomOperationsMatchMap(t *testing.T) {
+	db, path := openTestDB(t)
+	rng := rand.New(rand.NewSource(1))
+	want := make(map[int]string)
+
+	for i := 0; i < 5000; i++ {
+		key := rng.Intn(1500) + 1
+		if rng.Intn(3) == 0 {
+			deleted, err := db.Delete(key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := want[key]; ok != deleted {
+				t.Fatalf("delete %d: expected %v, got %v", key, ok, deleted)
+			}
+			delete(want, key)
+			continue
+		}
+		title := fmt.Sprintf("task %d rev %d %s", key, i, strings.Repeat("x", rng.Intn(200)))
+		if err := db.Set(key, models.Task{ID: key, Title: title}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want[key] = title
+	}
+
+	check := func(db *DB) {
+		ids := collect(t, db, 0)
+		if len(ids) != len(want) {
+			t.Fatalf("expected %d tasks, got %d", len(want), len(ids))
+		}
+		for i, id := range ids {
+			if i > 0 && ids[i-1] >= id {
+				t.Fatalf("scan is not in ascending order at %d", i)
+			}
+			got, ok, err := db.Get(id)
+			if err != nil || !ok || got.Title != want[id] {
+				t.Fatalf("task %d: expected %q, got %q (%v)", id, want[id], got.Title, err)
+			}
+		}
+	}
+	check(db)
+	db.Close()
+
+	reopened, err := Open(path, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reopened.Close()
+	check(reopened)
+}
+
+func TestDeleteEverythingReusesPages(t *testing.T) {
+	db, path := openTestDB(t)
+	defer db.Close()
+
+	for round := 0; round < 5; round++ {
+		for i := 1; i <= 500; i++ {
+			db.Set(i, models.Task{ID: i, Title: strings.Repeat("y", 100)})
+		}
+		for i := 1; i <= 500; i++ {
+			db.Delete(i)
+		}
+	}
+	if ids := collect(t, db, 0); len(ids) != 0 {
+		t.Fatalf("expected empty tree, got %d tasks", len(ids))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Size() > 200*pageSize {
+		t.Errorf("expected freed pages to be reused, file grew to %d pages", info.Size()/pageSize)
+	}
+}
+
+func TestLargeValuesUseOverflowPages(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+
+	big := strings.Repeat("z", 3*pageSize)
+	for i := 1; i <= 5; i++ {
+		if err := db.Set(i, models.Task{ID: i, Description: big}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for i := 1; i <= 5; i++ {
+		got, ok, err := db.Get(i)
+		if err != nil || !ok || got.Description != big {
+			t.Fatalf("task %d: large value did not round-trip (%v)", i, err)
+		}
+	}
+}
+
+func TestScanSeesConsistentVersion(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+	for i := 1; i <= 300; i++ {
+		db.Set(i, models.Task{ID: i, Title: "before"})
+	}
+
+	seen := 0
+	err := db.Scan(0, func(task models.Task) bool {
+		if task.Title != "before" {
+			t.Fatalf("task %d: reader observed a later commit", task.ID)
+		}
+		seen++
+		if seen == 1 {
+			for i := 1; i <= 300; i++ {
+				if i%2 == 0 {
+					db.Delete(i)
+				} else {
+					db.Set(i, models.Task{ID: i, Title: "after"})
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if seen != 300 {
+		t.Errorf("expected reader to see all 300 tasks, got %d", seen)
+	}
+	if ids := collect(t, db, 0); len(ids) != 150 {
+		t.Errorf("expected 150 tasks after the writes, got %d", len(ids))
+	}
+}
+
+func TestScanAfterKey(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+	for i := 1; i <= 1000; i++ {
+		db.Set(i, models.Task{ID: i})
+	}
+	ids := collect(t, db, 990)
+	if len(ids) != 10 || ids[0] != 991 {
+		t.Errorf("expected 991..1000, got %v", ids)
+	}
+}
+
+func TestTornMetaFallsBackToPreviousVersion(t *testing.T) {
+	db, path := openTestDB(t)
+	db.Set(1, models.Task{ID: 1, Title: "first"})
+	db.Set(2, models.Task{ID: 2, Title: "second"})
+	txid := db.meta.txid
+	db.Close()
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file.WriteAt([]byte("garbage"), int64(txid%2)*pageSize+pageHeaderSize+20)
+	file.Close()
+
+	reopened, err := Open(path, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reopened.Close()
+	if _, ok, _ := reopened.Get(1); !ok {
+		t.Errorf("expected first commit to survive")
+	}
+	if _, ok, _ := reopened.Get(2); ok {
+		t.Errorf("expected torn commit to be ignored")
+	}
+}
+
+func TestUpdateRollsBack(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+	db.Set(1, models.Task{ID: 1, Title: "Task 1"})
+
+	err := db.Update(func(tx store.Tx) error {
+		tx.Delete(1)
+		tx.Set(2, models.Task{ID: 2})
+		if _, ok, _ := tx.Get(2); !ok {
+			t.Errorf("expected transaction to read its own write")
+		}
+		return fmt.Errorf("abort")
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, ok, _ := db.Get(1); !ok {
+		t.Errorf("expected delete to be rolled back")
+	}
+	if _, ok, _ := db.Get(2); ok {
+		t.Errorf("expected set to be rolled back")
+	}
+}
+
+func TestOpenThroughRegistry(t *testing.T) {
+	driver, err := store.Open(DriverName, &store.Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer driver.Close()
+	id, err := driver.NextID()
+	if err != nil || id != 1 {
+		t.Errorf("expected first id 1, got %d (%v)", id, err)
+	}
+}
+
+func TestDeepTree(t *testing.T) {
+	db, path := openTestDB(t)
+	const n = 60000
+	for start := 1; start <= n; start += 1000 {
+		err := db.Update(func(tx store.Tx) error {
+			for i := start; i < start+1000; i++ {
+				if err := tx.Set(i, models.Task{ID: i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	rng := rand.New(rand.NewSource(2))
+	removed := make(map[int]bool)
+	db.Update(func(tx store.Tx) error {
+		for _, i := range rng.Perm(n)[:n/2] {
+			tx.Delete(i + 1)
+			removed[i+1] = true
+		}
+		return nil
+	})
+	db.Close()
+
+	reopened, err := Open(path, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reopened.Close()
+	ids := collect(t, reopened, 0)
+	if len(ids) != n/2 {
+		t.Fatalf("expected %d tasks, got %d", n/2, len(ids))
+	}
+	for _, id := range ids {
+		if removed[id] {
+			t.Fatalf("deleted task %d is still present", id)
+		}
+	}
+}
+
+func TestTxScanReadsOwnWrites(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+	for i := 1; i <= 100; i++ {
+		db.Set(i, models.Task{ID: i})
+	}
+
+	err := db.Update(func(tx store.Tx) error {
+		for i := 1; i <= 100; i += 2 {
+			tx.Delete(i)
+		}
+		tx.Set(101, models.Task{ID: 101})
+		count := 0
+		tx.Scan(0, func(task models.Task) bool {
+			if task.ID%2 == 1 && task.ID != 101 {
+				t.Errorf("scan returned deleted task %d", task.ID)
+			}
+			count++
+			return true
+		})
+		if count != 51 {
+			t.Errorf("expected 51 tasks inside the transaction, got %d", count)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestViewIsIsolatedFromCommits(t *testing.T) {
+	db, _ := openTestDB(t)
+	defer db.Close()
+	db.Set(1, models.Task{ID: 1, Title: "before"})
+
+	err := db.View(func(tx store.ReadTx) error {
+		db.Set(1, models.Task{ID: 1, Title: "after"})
+		db.Set(2, models.Task{ID: 2})
+		task, _, err := tx.Get(1)
+		if task.Title != "before" {
+			t.Errorf("expected the view to keep its version, got %q", task.Title)
+		}
+		if _, ok, _ := tx.Get(2); ok {
+			t.Errorf("expected a later commit to be invisible")
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestRandomTransactionsMatchMap mixes sets and deletes inside transactions
+// and compares the tree with a map while the transaction is open, after each
+// commit and after reopening. Deletes empty and merge the leftmost children
+// of branches, whose lower bound the branch no longer keeps exact.
+func TestRandomTransactionsMatchMap(t *testing.T) {
+	for seed := int64(1); seed <= 30; seed++ {
+		t.Run(fmt.Sprint(seed), func(t *testing.T) {
+			db, path := openTestDB(t)
+			rng := rand.New(rand.NewSource(seed))
+			want := make(map[int]string)
+
+			check := func(tx store.ReadTx, when string) {
+				t.Helper()
+				count := 0
+				err := tx.Scan(0, func(task models.Task) bool {
+					if want[task.ID] != task.Title {
+						t.Fatalf("%s: scan returned task %d %q, want %q", when, task.ID, task.Title, want[task.ID])
+					}
+					count++
+					return true
+				})
+				if err != nil || count != len(want) {
+					t.Fatalf("%s: scan returned %d tasks, want %d (%v)", when, count, len(want), err)
+				}
+				for key := 1; key <= 600; key++ {
+					got, ok, err := tx.Get(key)
+					if title, exists := want[key]; err != nil || ok != exists || got.Title != title {
+						t.Fatalf("%s: task %d: got %q %v, want %q %v (%v)", when, key, got.Title, ok, title, exists, err)
+					}
+				}
+			}
+
+			for round := 0; round < 6; round++ {
+				err := db.Update(func(tx store.Tx) error {
+					for i := 0; i < 500; i++ {
+						key := rng.Intn(600) + 1
+						// Deletes win in later rounds, so the tree shrinks again.
+						if rng.Intn(6) < round+1 {
+							if _, err := tx.Delete(key); err != nil {
+								return err
+							}
+							delete(want, key)
+							continue
+						}
+						title := fmt.Sprintf("%d/%d/%d %s", key, round, i, strings.Repeat("x", rng.Intn(300)))
+						if err := tx.Set(key, models.Task{ID: key, Title: title}); err != nil {
+							return err
+						}
+						want[key] = title
+					}
+					check(tx, fmt.Sprintf("round %d before commit", round))
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				db.View(func(tx store.ReadTx) error {
+					check(tx, fmt.Sprintf("round %d after commit", round))
+					return nil
+				})
+			}
+			db.Close()
+
+			reopened, err := Open(path, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer reopened.Close()
+			reopened.View(func(tx store.ReadTx) error {
+				check(tx, "after reopening")
+				return nil
+			})
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package bptree
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+const (
+	pageSize       = 4096
+	pageHeaderSize = 16
+
+	magic   uint32 = 0x54534B42
+	version uint32 = 1
+
+	leafPageFlag     uint16 = 0x01
+	branchPageFlag   uint16 = 0x02
+	metaPageFlag     uint16 = 0x04
+	freelistPageFlag uint16 = 0x08
+
+	leafElementSize   = 12
+	branchElementSize = 16
+	metaSize          = 64
+)
+
+type pgid uint64
+
+// pageHeader starts every page. A node larger than one page occupies
+// overflow further contiguous pages.
+type pageHeader struct {
+	id       pgid
+	flags    uint16
+	count    uint16
+	overflow uint32
+}
+
+func (h pageHeader) put(buf []byte) {
+	binary.LittleEndian.PutUint64(buf[0:8], uint64(h.id))
+	binary.LittleEndian.PutUint16(buf[8:10], h.flags)
+	binary.LittleEndian.PutUint16(buf[10:12], h.count)
+	binary.LittleEndian.PutUint32(buf[12:16], h.overflow)
+}
+
+func readPageHeader(buf []byte) pageHeader {
+	return pageHeader{
+		id:       pgid(binary.LittleEndian.Uint64(buf[0:8])),
+		flags:    binary.LittleEndian.Uint16(buf[8:10]),
+		count:    binary.LittleEndian.Uint16(buf[10:12]),
+		overflow: binary.LittleEndian.Uint32(buf[12:16]),
+	}
+}
+
+func pagesFor(size int) int {
+	return (size + pageSize - 1) / pageSize
+}
+
+// meta describes one committed version of the tree. Two copies live in
+// pages 0 and 1 and are written alternately, so a torn meta write falls
+// back to the previous version.
+type meta struct {
+	root     pgid
+	freelist pgid
+	// high is the first page id past the end of the allocated file.
+	high   pgid
+	txid   uint64
+	nextID uint64
+}
+
+var errInvalidMeta = errors.New("bptree: invalid meta page")
+
+func (m meta) encode() []byte {
+	buf := make([]byte, pageSize)
+	pageHeader{id: pgid(m.txid % 2), flags: metaPageFlag}.put(buf)
+	b := buf[pageHeaderSize:]
+	binary.LittleEndian.PutUint32(b[0:4], magic)
+	binary.LittleEndian.PutUint32(b[4:8], version)
+	binary.LittleEndian.PutUint32(b[8:12], pageSize)
+	binary.LittleEndian.PutUint64(b[16:24], uint64(m.root))
+	binary.LittleEndian.PutUint64(b[24:32], uint64(m.freelist))
+	binary.LittleEndian.PutUint64(b[32:40], uint64(m.high))
+	binary.LittleEndian.PutUint64(b[40:48], m.txid)
+	binary.LittleEndian.PutUint64(b[48:56], m.nextID)
+	binary.LittleEndian.PutUint64(b[56:64], checksum(b[:56]))
+	return buf
+}
+
+func decodeMeta(buf []byte) (meta, error) {
+	if readPageHeader(buf).flags != metaPageFlag {
+		return meta{}, errInvalidMeta
+	}
+	b := buf[pageHeaderSize:]
+	if binary.LittleEndian.Uint32(b[0:4]) != magic {
+		return meta{}, errInvalidMeta
+	}
+	if v := binary.LittleEndian.Uint32(b[4:8]); v != version {
+		return meta{}, fmt.Errorf("bptree: unsupported file version %d", v)
+	}
+	if ps := binary.LittleEndian.Uint32(b[8:12]); ps != pageSize {
+		return meta{}, fmt.Errorf("bptree: unsupported page size %d", ps)
+	}
+	if binary.LittleEndian.Uint64(b[56:64]) != checksum(b[:56]) {
+		return meta{}, errInvalidMeta
+	}
+	return meta{
+		root:     pgid(binary.LittleEndian.Uint64(b[16:24])),
+		freelist: pgid(binary.LittleEndian.Uint64(b[24:32])),
+		high:     pgid(binary.LittleEndian.Uint64(b[32:40])),
+		txid:     binary.LittleEndian.Uint64(b[40:48]),
+		nextID:   binary.LittleEndian.Uint64(b[48:56]),
+	}, nil
+}
+
+func checksum(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+// node is the decoded form of a leaf or branch page. Branch keys are lower
+// bounds: every key under children[i] is >= keys[i] and < keys[i+1]; keys[0]
+// is never consulted when searching.
+type node struct {
+	leaf     bool
+	keys     []int64
+	values   [][]byte
+	children []int64
+
+	// id and overflow describe the page the node was read from, so the
+	// pages can be freed once the node is rewritten.
+	id       pgid
+	overflow uint32
+	dirty    bool
+}
+
+func (n *node) size() int {
+	if !n.leaf {
+		return pageHeaderSize + branchElementSize*len(n.keys)
+	}
+	size := pageHeaderSize
+	for _, v := range n.values {
+		size += leafElementSize + len(v)
+	}
+	return size
+}
+
+// index returns the position of key in a leaf, or the child that may hold
+// key in a branch.
+func (n *node) index(key int64) (int, bool) {
+	if !n.leaf {
+		// keys[0] goes stale when the first child is dropped or merged, so
+		// the search starts at keys[1]: the child is the last one whose lower
+		// bound is at most key.
+		lo, hi := 1, len(n.keys)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if n.keys[mid] <= key {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		return lo - 1, false
+	}
+	lo, hi := 0, len(n.keys)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if n.keys[mid] < key {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, lo < len(n.keys) && n.keys[lo] == key
+}
+
+func (n *node) encode(id pgid) []byte {
+	size := n.size()
+	buf := make([]byte, pagesFor(size)*pageSize)
+	flags := branchPageFlag
+	if n.leaf {
+		flags = leafPageFlag
+	}
+	pageHeader{id: id, flags: flags, count: uint16(len(n.keys)), overflow: uint32(pagesFor(size) - 1)}.put(buf)
+	b := buf[pageHeaderSize:]
+	for i, key := range n.keys {
+		binary.LittleEndian.PutUint64(b[0:8], uint64(key))
+		if n.leaf {
+			binary.LittleEndian.PutUint32(b[8:12], uint32(len(n.values[i])))
+			copy(b[leafElementSize:], n.values[i])
+			b = b[leafElementSize+len(n.values[i]):]
+		} else {
+			binary.LittleEndian.PutUint64(b[8:16], uint64(n.children[i]))
+			b = b[branchElementSize:]
+		}
+	}
+	return buf
+}
+
+func decodeNode(buf []byte) (*node, error) {
+	h := readPageHeader(buf)
+	n := &node{id: h.id, overflow: h.overflow}
+	switch h.flags {
+	case leafPageFlag:
+		n.leaf = true
+	case branchPageFlag:
+	default:
+		return nil, fmt.Errorf("bptree: page %d is not a tree node (flags %#x)", h.id, h.flags)
+	}
+	b := buf[pageHeaderSize:]
+	n.keys = make([]int64, h.count)
+	if n.leaf {
+		n.values = make([][]byte, h.count)
+	} else {
+		n.children = make([]int64, h.count)
+	}
+	for i := range n.keys {
+		if n.leaf {
+			if len(b) < leafElementSize {
+				return nil, fmt.Errorf("bptree: page %d is truncated", h.id)
+			}
+			n.keys[i] = int64(binary.LittleEndian.Uint64(b[0:8]))
+			vlen := int(binary.LittleEndian.Uint32(b[8:12]))
+			if len(b) < leafElementSize+vlen {
+				return nil, fmt.Errorf("bptree: page %d is truncated", h.id)
+			}
+			n.values[i] = append([]byte(nil), b[leafElementSize:leafElementSize+vlen]...)
+			b = b[leafElementSize+vlen:]
+		} else {
+			if len(b) < branchElementSize {
+				return nil, fmt.Errorf("bptree: page %d is truncated", h.id)
+			}
+			n.keys[i] = int64(binary.LittleEndian.Uint64(b[0:8]))
+			n.children[i] = int64(binary.LittleEndian.Uint64(b[8:16]))
+			b = b[branchElementSize:]
+		}
+	}
+	return n, nil
+}
+
+func encodeFreelist(id pgid, ids []pgid) []byte {
+	size := pageHeaderSize + 8 + 8*len(ids)
+	buf := make([]byte, pagesFor(size)*pageSize)
+	pageHeader{id: id, flags: freelistPageFlag, overflow: uint32(pagesFor(size) - 1)}.put(buf)
+	b := buf[pageHeaderSize:]
+	binary.LittleEndian.PutUint64(b[0:8], uint64(len(ids)))
+	for i, id := range ids {
+		binary.LittleEndian.PutUint64(b[8+8*i:], uint64(id))
+	}
+	return buf
+}
+
+func decodeFreelist(buf []byte) ([]pgid, uint32, error) {
+	h := readPageHeader(buf)
+	if h.flags != freelistPageFlag {
+		return nil, 0, fmt.Errorf("bptree: page %d is not a freelist (flags %#x)", h.id, h.flags)
+	}
+	b := buf[pageHeaderSize:]
+	count := binary.LittleEndian.Uint64(b[0:8])
+	if uint64(len(b)-8)/8 < count {
+		return nil, 0, fmt.Errorf("bptree: freelist page %d is truncated", h.id)
+	}
+	ids := make([]pgid, count)
+	for i := range ids {
+		ids[i] = pgid(binary.LittleEndian.Uint64(b[8+8*i:]))
+	}
+	return ids, h.overflow, nil
+}
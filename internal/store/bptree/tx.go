@@ -0,0 +1,380 @@
+package bptree
+
+import (
+	"encoding/json"
+	"slices"
+
+	"task-manager/internal/models"
+)
+
+// minFill is the size below which a node is merged with a sibling.
+const minFill = pageSize / 4
+
+// writeTx edits a private copy of the nodes it touches. Nodes created during
+// the transaction get negative ids until they are written out on commit.
+type writeTx struct {
+	db      *DB
+	meta    meta
+	free    []pgid
+	freed   []pgid
+	nodes   map[int64]*node
+	lastTmp int64
+	changed bool
+}
+
+type pathStep struct {
+	id    int64
+	index int
+}
+
+func (tx *writeTx) node(id int64) (*node, error) {
+	if n, ok := tx.nodes[id]; ok {
+		return n, nil
+	}
+	n, err := tx.db.readNode(id)
+	if err != nil {
+		return nil, err
+	}
+	tx.nodes[id] = n
+	return n, nil
+}
+
+func (tx *writeTx) newNode(n *node) int64 {
+	tx.lastTmp--
+	n.dirty = true
+	tx.nodes[tx.lastTmp] = n
+	return tx.lastTmp
+}
+
+// forget drops a node that is no longer part of the tree.
+func (tx *writeTx) forget(id int64) {
+	if n := tx.nodes[id]; n != nil && id > 0 {
+		tx.freePages(n.id, n.overflow)
+	}
+	delete(tx.nodes, id)
+}
+
+func (tx *writeTx) freePages(id pgid, overflow uint32) {
+	for i := pgid(0); i <= pgid(overflow); i++ {
+		tx.freed = append(tx.freed, id+i)
+	}
+}
+
+// descend loads the path from the root to the leaf responsible for key and
+// marks every node on it dirty.
+func (tx *writeTx) descend(key int64) ([]pathStep, int64, error) {
+	var path []pathStep
+	id := int64(tx.meta.root)
+	for {
+		n, err := tx.node(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		n.dirty = true
+		if n.leaf {
+			return path, id, nil
+		}
+		i, _ := n.index(key)
+		path = append(path, pathStep{id: id, index: i})
+		id = n.children[i]
+	}
+}
+
+func (tx *writeTx) Get(key int) (models.Task, bool, error) {
+	return get(tx.node, int64(tx.meta.root), int64(key))
+}
+
+func (tx *writeTx) Scan(after int, fn func(models.Task) bool) error {
+	if tx.meta.root == 0 {
+		return nil
+	}
+	_, err := scan(tx.node, tx.root(), int64(after), fn)
+	return err
+}
+
+func (tx *writeTx) Set(key int, value models.Task) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	tx.changed = true
+	k := int64(key)
+	if tx.meta.root == 0 {
+		tx.setRoot(tx.newNode(&node{leaf: true}))
+	}
+	path, id, err := tx.descend(k)
+	if err != nil {
+		return err
+	}
+	leaf := tx.nodes[id]
+	i, found := leaf.index(k)
+	if found {
+		leaf.values[i] = data
+	} else {
+		leaf.keys = slices.Insert(leaf.keys, i, k)
+		leaf.values = slices.Insert(leaf.values, i, data)
+	}
+	return tx.split(path, id)
+}
+
+func (tx *writeTx) Delete(key int) (bool, error) {
+	if tx.meta.root == 0 {
+		return false, nil
+	}
+	k := int64(key)
+	path, id, err := tx.descend(k)
+	if err != nil {
+		return false, err
+	}
+	leaf := tx.nodes[id]
+	i, found := leaf.index(k)
+	if !found {
+		return false, nil
+	}
+	tx.changed = true
+	leaf.keys = slices.Delete(leaf.keys, i, i+1)
+	leaf.values = slices.Delete(leaf.values, i, i+1)
+	return true, tx.rebalance(path, id)
+}
+
+func (tx *writeTx) NextID() (int, error) {
+	tx.changed = true
+	id := tx.meta.nextID
+	tx.meta.nextID++
+	return int(id), nil
+}
+
+func (tx *writeTx) setRoot(id int64) {
+	tx.meta.root = pgid(id)
+}
+
+func (tx *writeTx) root() int64 {
+	return int64(tx.meta.root)
+}
+
+// split breaks oversized nodes in two, from the node at id up to the root.
+func (tx *writeTx) split(path []pathStep, id int64) error {
+	for {
+		n := tx.nodes[id]
+		if n.size() <= pageSize || len(n.keys) < 2 {
+			return nil
+		}
+		right := splitNode(n)
+		rightID := tx.newNode(right)
+		if len(path) == 0 {
+			root := &node{keys: []int64{n.keys[0], right.keys[0]}, children: []int64{id, rightID}}
+			tx.setRoot(tx.newNode(root))
+			return nil
+		}
+		step := path[len(path)-1]
+		path = path[:len(path)-1]
+		parent := tx.nodes[step.id]
+		parent.keys = slices.Insert(parent.keys, step.index+1, right.keys[0])
+		parent.children = slices.Insert(parent.children, step.index+1, rightID)
+		id = step.id
+	}
+}
+
+// splitNode moves the upper half of n, by encoded size, into a new node.
+func splitNode(n *node) *node {
+	half := n.size() / 2
+	size := pageHeaderSize
+	at := 1
+	for ; at < len(n.keys)-1; at++ {
+		if n.leaf {
+			size += leafElementSize + len(n.values[at-1])
+		} else {
+			size += branchElementSize
+		}
+		if size >= half {
+			break
+		}
+	}
+	right := &node{leaf: n.leaf, keys: slices.Clone(n.keys[at:])}
+	n.keys = n.keys[:at:at]
+	if n.leaf {
+		right.values = slices.Clone(n.values[at:])
+		n.values = n.values[:at:at]
+	} else {
+		right.children = slices.Clone(n.children[at:])
+		n.children = n.children[:at:at]
+	}
+	return right
+}
+
+// rebalance merges underfilled nodes into a sibling after a delete, from the
+// leaf at id up to the root, and shrinks the tree when the root is left with
+// a single child.
+func (tx *writeTx) rebalance(path []pathStep, id int64) error {
+	for len(path) > 0 {
+		n := tx.nodes[id]
+		if n.size() >= minFill {
+			return nil
+		}
+		step := path[len(path)-1]
+		path = path[:len(path)-1]
+		parent := tx.nodes[step.id]
+
+		switch {
+		case len(n.keys) == 0:
+			parent.keys = slices.Delete(parent.keys, step.index, step.index+1)
+			parent.children = slices.Delete(parent.children, step.index, step.index+1)
+			tx.forget(id)
+		case len(parent.children) > 1:
+			left, right := step.index-1, step.index
+			if step.index == 0 {
+				left, right = 0, 1
+			}
+			if err := tx.merge(parent, left, right); err != nil {
+				return err
+			}
+			if err := tx.split(append(path, pathStep{id: step.id, index: left}), parent.children[left]); err != nil {
+				return err
+			}
+		}
+		id = step.id
+	}
+
+	root := tx.nodes[id]
+	for !root.leaf && len(root.children) == 1 {
+		child := root.children[0]
+		tx.forget(id)
+		id = child
+		var err error
+		if root, err = tx.node(id); err != nil {
+			return err
+		}
+	}
+	if root.leaf && len(root.keys) == 0 {
+		tx.forget(id)
+		id = 0
+	}
+	tx.setRoot(id)
+	return nil
+}
+
+// merge appends the child at right onto the child at left and removes right
+// from parent.
+func (tx *writeTx) merge(parent *node, left, right int) error {
+	l, err := tx.node(parent.children[left])
+	if err != nil {
+		return err
+	}
+	r, err := tx.node(parent.children[right])
+	if err != nil {
+		return err
+	}
+	l.dirty = true
+	keys := slices.Clone(r.keys)
+	if !r.leaf && len(keys) > 0 {
+		keys[0] = parent.keys[right]
+	}
+	l.keys = append(l.keys, keys...)
+	l.values = append(l.values, r.values...)
+	l.children = append(l.children, r.children...)
+	tx.forget(parent.children[right])
+	parent.keys = slices.Delete(parent.keys, right, right+1)
+	parent.children = slices.Delete(parent.children, right, right+1)
+	return nil
+}
+
+func (tx *writeTx) allocate(count int) pgid {
+	for i := 0; i+count <= len(tx.free); i++ {
+		if tx.free[i+count-1]-tx.free[i] == pgid(count-1) {
+			id := tx.free[i]
+			tx.free = slices.Delete(tx.free, i, i+count)
+			return id
+		}
+	}
+	id := tx.meta.high
+	tx.meta.high += pgid(count)
+	return id
+}
+
+// spill writes the dirty nodes under id to freshly allocated pages and
+// returns the page the subtree root now lives in.
+func (tx *writeTx) spill(id int64) (pgid, error) {
+	n, ok := tx.nodes[id]
+	if !ok || !n.dirty {
+		return pgid(id), nil
+	}
+	for i, child := range n.children {
+		p, err := tx.spill(child)
+		if err != nil {
+			return 0, err
+		}
+		n.children[i] = int64(p)
+	}
+	if id > 0 {
+		tx.freePages(n.id, n.overflow)
+	}
+	p := tx.allocate(pagesFor(n.size()))
+	if _, err := tx.db.file.WriteAt(n.encode(p), int64(p)*pageSize); err != nil {
+		return 0, err
+	}
+	return p, nil
+}
+
+func (tx *writeTx) commit() error {
+	db := tx.db
+	if tx.meta.root != 0 {
+		root, err := tx.spill(tx.root())
+		if err != nil {
+			return err
+		}
+		tx.meta.root = root
+	}
+
+	if tx.meta.freelist != 0 {
+		buf, err := db.readPages(tx.meta.freelist)
+		if err != nil {
+			return err
+		}
+		tx.freePages(tx.meta.freelist, readPageHeader(buf).overflow)
+	}
+	db.mu.Lock()
+	reserved := len(tx.free) + len(tx.freed)
+	for _, ids := range db.pending {
+		reserved += len(ids)
+	}
+	db.mu.Unlock()
+	freelistID := tx.allocate(pagesFor(pageHeaderSize + 8 + 8*reserved))
+
+	// Everything not reachable from the new root is free after a restart,
+	// including pages still pinned by readers of this process.
+	db.mu.Lock()
+	all := slices.Concat(tx.free, tx.freed)
+	for _, ids := range db.pending {
+		all = append(all, ids...)
+	}
+	db.mu.Unlock()
+	slices.Sort(all)
+	if _, err := db.file.WriteAt(encodeFreelist(freelistID, all), int64(freelistID)*pageSize); err != nil {
+		return err
+	}
+	tx.meta.freelist = freelistID
+
+	if db.sync {
+		if err := db.file.Sync(); err != nil {
+			return err
+		}
+	}
+	tx.meta.txid++
+	if _, err := db.file.WriteAt(tx.meta.encode(), int64(tx.meta.txid%2)*pageSize); err != nil {
+		return err
+	}
+	if db.sync {
+		if err := db.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.meta = tx.meta
+	db.free = tx.free
+	if len(tx.freed) > 0 {
+		db.pending[tx.meta.txid] = tx.freed
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package store
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"task-manager/internal/models"
+)
+
+// Driver is a storage engine for tasks keyed by ID.
+type Driver interface {
+	Get(key int) (models.Task, bool, error)
+	Set(key int, value models.Task) error
+	Delete(key int) (bool, error)
+	// Scan calls fn for every task with a key greater than after, in
+	// ascending key order, until fn returns false.
+	Scan(after int, fn func(models.Task) bool) error
+	NextID() (int, error)
+	// Update runs fn in a read-write transaction. The changes made through
+	// tx are applied atomically if fn returns nil and discarded otherwise.
+	Update(fn func(tx Tx) error) error
+	// View runs fn in a read-only transaction that sees a single consistent
+	// version of the data.
+	View(fn func(tx ReadTx) error) error
+	Close() error
+}
+
+// ReadTx is the read side of a transaction.
+type ReadTx interface {
+	Get(key int) (models.Task, bool, error)
+	// Scan works like Driver.Scan; fn must not write through the
+	// transaction while the scan runs.
+	Scan(after int, fn func(models.Task) bool) error
+}
+
+// Tx is the view of a driver inside a transaction. Reads observe the
+// transaction's own uncommitted writes.
+type Tx interface {
+	ReadTx
+	Set(key int, value models.Task) error
+	Delete(key int) (bool, error)
+	NextID() (int, error)
+}
+
+var _ Driver = (*Store)(nil)
+
+type OpenFunc func(cfg *Config) (Driver, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]OpenFunc)
+)
+
+const DefaultDriver = "memory"
+
+func init() {
+	Register(DefaultDriver, func(cfg *Config) (Driver, error) {
+		return OpenStore(cfg)
+	})
+}
+
+// Register makes a driver available under name. It panics if name is
+// already taken, mirroring database/sql.
+func Register(name string, open OpenFunc) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if open == nil {
+		panic("store: Register open func is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("store: Register called twice for driver " + name)
+	}
+	drivers[name] = open
+}
+
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	return slices.Sorted(maps.Keys(drivers))
+}
+
+// Open opens the driver registered under name; an empty name selects
+// DefaultDriver.
+func Open(name string, cfg *Config) (Driver, error) {
+	if name == "" {
+		name = DefaultDriver
+	}
+	driversMu.RLock()
+	open, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown store driver %q (available: %v)", name, Drivers())
+	}
+	return open(cfg)
+}
+
+// memTx buffers writes until commit; a nil entry in writes is a delete.
+type memTx struct {
+	s       *Store
+	writes  map[int]*models.Task
+	order   []int
+	nextID  int
+	touched bool
+}
+
+func (tx *memTx) Get(key int) (models.Task, bool, error) {
+	if task, ok := tx.writes[key]; ok {
+		if task == nil {
+			return models.Task{}, false, nil
+		}
+		return *task, true, nil
+	}
+	task, ok := tx.s.tasks[key]
+	return task, ok, nil
+}
+
+// Scan merges the transaction's pending writes, which are few, into the
+// stored keys, so it costs the keys it visits rather than a sort of the map.
+func (tx *memTx) Scan(after int, fn func(models.Task) bool) error {
+	var pending []int
+	for key := range tx.writes {
+		if key > after {
+			pending = append(pending, key)
+		}
+	}
+	slices.Sort(pending)
+	stored := tx.s.keysAfter(after)
+	for len(stored) > 0 || len(pending) > 0 {
+		var key int
+		if len(pending) == 0 || len(stored) > 0 && stored[0] < pending[0] {
+			key, stored = stored[0], stored[1:]
+		} else {
+			key, pending = pending[0], pending[1:]
+			if len(stored) > 0 && stored[0] == key {
+				stored = stored[1:]
+			}
+		}
+		if task, ok, _ := tx.Get(key); ok && !fn(task) {
+			break
+		}
+	}
+	return nil
+}
+
+func (tx *memTx) Set(key int, value models.Task) error {
+	tx.write(key, &value)
+	return nil
+}
+
+func (tx *memTx) Delete(key int) (bool, error) {
+	_, ok, _ := tx.Get(key)
+	if !ok {
+		return false, nil
+	}
+	tx.write(key, nil)
+	return true, nil
+}
+
+func (tx *memTx) NextID() (int, error) {
+	id := tx.nextID
+	tx.nextID++
+	tx.touched = true
+	return id, nil
+}
+
+func (tx *memTx) write(key int, task *models.Task) {
+	if _, ok := tx.writes[key]; !ok {
+		tx.order = append(tx.order, key)
+	}
+	tx.writes[key] = task
+}
+
+func (tx *memTx) records() []record {
+	var recs []record
+	if tx.touched {
+		recs = append(recs, record{Op: opNextID, NextID: tx.nextID})
+	}
+	for _, key := range tx.order {
+		if task := tx.writes[key]; task != nil {
+			recs = append(recs, record{Op: opSet, Key: key, Task: task})
+		} else {
+			recs = append(recs, record{Op: opDelete, Key: key})
+		}
+	}
+	return recs
+}
+
+func (s *Store) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memTx{s: s, writes: make(map[int]*models.Task), nextID: s.nextID}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	recs := tx.records()
+	if len(recs) == 0 {
+		return nil
+	}
+	batch := record{Op: opBatch, Batch: recs}
+	if err := s.log(batch); err != nil {
+		return err
+	}
+	return s.apply(batch)
+}
+
+// View holds the read lock while fn runs, so fn should not block for long.
+func (s *Store) View(fn func(tx ReadTx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(memView{s: s})
+}
+
+// memView reads the map directly; its caller holds s.mu.
+type memView struct {
+	s *Store
+}
+
+func (v memView) Get(key int) (models.Task, bool, error) {
+	task, ok := v.s.tasks[key]
+	return task, ok, nil
+}
+
+func (v memView) Scan(after int, fn func(models.Task) bool) error {
+	for _, key := range v.s.keysAfter(after) {
+		if !fn(v.s.tasks[key]) {
+			break
+		}
+	}
+	return nil
+}
+
+// scanChunk is how many tasks Store.Scan copies per hold of the read lock.
+const scanChunk = 256
+
+// Scan walks the tasks in chunks: each chunk is copied under the read lock
+// and handed to fn without it, so a slow consumer does not hold up writers
+// and the store is never copied whole. A chunk is consistent in itself;
+// writes between chunks may or may not be seen.
+func (s *Store) Scan(after int, fn func(models.Task) bool) error {
+	chunk := make([]models.Task, 0, scanChunk)
+	for {
+		s.mu.RLock()
+		keys := s.keysAfter(after)
+		chunk = chunk[:0]
+		for _, key := range keys[:min(len(keys), scanChunk)] {
+			chunk = append(chunk, s.tasks[key])
+			after = key
+		}
+		s.mu.RUnlock()
+
+		for _, task := range chunk {
+			if !fn(task) {
+				return nil
+			}
+		}
+		if len(chunk) < scanChunk {
+			return nil
+		}
+	}
+}
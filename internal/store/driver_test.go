@@ -0,0 +1,193 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"task-manager/internal/models"
+)
+
+func TestOpenUnknownDriver(t *testing.T) {
+	if _, err := Open("no-such-driver", nil); err == nil {
+		t.Errorf("expected error for unknown driver")
+	}
+}
+
+func TestOpenDefaultDriver(t *testing.T) {
+	driver, err := Open("", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer driver.Close()
+	if _, ok := driver.(*Store); !ok {
+		t.Errorf("expected default driver to be the in-memory store, got %T", driver)
+	}
+}
+
+func TestUpdateCommits(t *testing.T) {
+	store := NewStore()
+	err := store.Update(func(tx Tx) error {
+		id, _ := tx.NextID()
+		if err := tx.Set(id, models.Task{ID: id, Title: "Task"}); err != nil {
+			return err
+		}
+		got, ok, _ := tx.Get(id)
+		if !ok || got.Title != "Task" {
+			t.Errorf("expected transaction to read its own write, got %v", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok, _ := store.Get(1); !ok {
+		t.Errorf("expected committed task to be visible")
+	}
+	if id, _ := store.NextID(); id != 2 {
+		t.Errorf("expected next id 2, got %d", id)
+	}
+}
+
+func TestUpdateRollsBack(t *testing.T) {
+	store := NewStore()
+	store.Set(1, models.Task{ID: 1, Title: "Task 1"})
+
+	errBoom := errors.New("boom")
+	err := store.Update(func(tx Tx) error {
+		tx.Delete(1)
+		tx.Set(2, models.Task{ID: 2, Title: "Task 2"})
+		tx.NextID()
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected errBoom, got %v", err)
+	}
+	if _, ok, _ := store.Get(1); !ok {
+		t.Errorf("expected delete to be rolled back")
+	}
+	if _, ok, _ := store.Get(2); ok {
+		t.Errorf("expected set to be rolled back")
+	}
+	if id, _ := store.NextID(); id != 1 {
+		t.Errorf("expected next id to be rolled back, got %d", id)
+	}
+}
+
+func TestUpdateIsReplayedAtomically(t *testing.T) {
+	cfg := &Config{Dir: t.TempDir(), Sync: SyncAlways}
+	store, err := OpenStore(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Update(func(tx Tx) error {
+		for i := 0; i < 3; i++ {
+			id, _ := tx.NextID()
+			tx.Set(id, models.Task{ID: id, Title: "Task"})
+		}
+		return nil
+	})
+	store.Close()
+
+	reopened, err := OpenStore(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reopened.Close()
+	var ids []int
+	reopened.Scan(0, func(task models.Task) bool {
+		ids = append(ids, task.ID)
+		return true
+	})
+	if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Errorf("expected tasks 1..3 in order, got %v", ids)
+	}
+}
+
+func TestScanAfter(t *testing.T) {
+	store := NewStore()
+	for _, id := range []int{5, 1, 3, 2, 4} {
+		store.Set(id, models.Task{ID: id})
+	}
+	var ids []int
+	store.Scan(2, func(task models.Task) bool {
+		ids = append(ids, task.ID)
+		return len(ids) < 2
+	})
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 4 {
+		t.Errorf("expected [3 4], got %v", ids)
+	}
+}
+
+func TestScanWalksChunks(t *testing.T) {
+	store := NewStore()
+	total := 2*scanChunk + 10
+	for id := 1; id <= total; id++ {
+		store.Set(id, models.Task{ID: id})
+	}
+
+	// fn runs without the lock, so it may write; a key removed ahead of the
+	// current chunk is not seen.
+	var ids []int
+	store.Scan(0, func(task models.Task) bool {
+		if task.ID == 1 {
+			store.Delete(scanChunk + 1)
+		}
+		ids = append(ids, task.ID)
+		return true
+	})
+	if len(ids) != total-1 || slices.Contains(ids, scanChunk+1) || !slices.IsSorted(ids) {
+		t.Errorf("expected %d sorted tasks without %d, got %d", total-1, scanChunk+1, len(ids))
+	}
+}
+
+func TestTxScanReadsOwnWrites(t *testing.T) {
+	store := NewStore()
+	store.Set(1, models.Task{ID: 1, Title: "one"})
+	store.Set(2, models.Task{ID: 2, Title: "two"})
+
+	err := store.Update(func(tx Tx) error {
+		tx.Delete(1)
+		tx.Set(2, models.Task{ID: 2, Title: "two, edited"})
+		tx.Set(3, models.Task{ID: 3, Title: "three"})
+
+		var titles []string
+		tx.Scan(0, func(task models.Task) bool {
+			titles = append(titles, task.Title)
+			return true
+		})
+		if len(titles) != 2 || titles[0] != "two, edited" || titles[1] != "three" {
+			t.Errorf("expected the transaction to scan its own writes, got %v", titles)
+		}
+		return errors.New("abort")
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, ok, _ := store.Get(1); !ok {
+		t.Errorf("expected delete to be rolled back")
+	}
+}
+
+func TestView(t *testing.T) {
+	store := NewStore()
+	store.Set(1, models.Task{ID: 1, Title: "one"})
+	store.Set(2, models.Task{ID: 2, Title: "two"})
+
+	var ids []int
+	err := store.View(func(tx ReadTx) error {
+		if task, ok, _ := tx.Get(2); !ok || task.Title != "two" {
+			t.Errorf("expected task 2, got %v %v", task, ok)
+		}
+		return tx.Scan(0, func(task models.Task) bool {
+			ids = append(ids, task.ID)
+			return true
+		})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != 1 {
+		t.Errorf("expected tasks 1 and 2, got %v", ids)
+	}
+}
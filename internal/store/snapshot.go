@@ -0,0 +1,245 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-manager/internal/models"
+	"task-manager/pkg/logger"
+)
+
+const (
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".json"
+)
+
+// snapshot is the state of the store before WAL segment Seq, so recovery
+// loads it and replays segments Seq and later.
+type snapshot struct {
+	Seq    uint64              `json:"seq"`
+	NextID int                 `json:"next_id"`
+	Tasks  map[int]models.Task `json:"tasks"`
+}
+
+func snapshotName(seq uint64) string {
+	return fmt.Sprintf("%s%020d%s", snapshotPrefix, seq, snapshotSuffix)
+}
+
+// listSeqs returns the sequence numbers of files in dir named
+// prefix<seq>suffix, in ascending order.
+func listSeqs(dir, prefix, suffix string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var seqs []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+	return seqs, nil
+}
+
+func readSnapshot(path string) (snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot{}, err
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snapshot{}, err
+	}
+	return snap, nil
+}
+
+// writeSnapshot persists snap atomically: it is written to a temporary file,
+// fsynced and only then renamed into place.
+func writeSnapshot(dir string, snap snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, snapshotName(snap.Seq))
+	tmp, err := os.CreateTemp(dir, snapshotPrefix+"*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Snapshot writes the current state to disk and compacts the write-ahead
+// log. Readers are never blocked; writers wait only while the task map is
+// copied and the log is rotated.
+func (s *Store) Snapshot() error {
+	s.snapMu.Lock()
+	defer s.snapMu.Unlock()
+
+	s.mu.RLock()
+	if s.wal == nil || s.wal.empty() {
+		s.mu.RUnlock()
+		return nil
+	}
+	snap := snapshot{NextID: s.nextID, Tasks: maps.Clone(s.tasks)}
+	seq, err := s.wal.rotate()
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	snap.Seq = seq
+	if err := writeSnapshot(s.cfg.Dir, snap); err != nil {
+		return err
+	}
+	return s.compact()
+}
+
+// compact removes snapshots beyond the retention limit and every WAL
+// segment that the oldest retained snapshot already covers.
+func (s *Store) compact() error {
+	snaps, err := listSeqs(s.cfg.Dir, snapshotPrefix, snapshotSuffix)
+	if err != nil {
+		return err
+	}
+	retain := max(s.cfg.SnapshotRetain, 1)
+	if len(snaps) > retain {
+		for _, seq := range snaps[:len(snaps)-retain] {
+			if err := os.Remove(filepath.Join(s.cfg.Dir, snapshotName(seq))); err != nil {
+				return err
+			}
+		}
+		snaps = snaps[len(snaps)-retain:]
+	}
+	if len(snaps) == 0 {
+		return nil
+	}
+	segments, err := listSeqs(s.cfg.Dir, segmentPrefix, segmentSuffix)
+	if err != nil {
+		return err
+	}
+	for _, seq := range segments {
+		if seq >= snaps[0] {
+			break
+		}
+		if err := os.Remove(filepath.Join(s.cfg.Dir, segmentName(seq))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) snapshotLoop(interval time.Duration) {
+	defer close(s.snapDoneCh)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Snapshot(); err != nil {
+				logger.Error("store: snapshot failed", "error", err)
+			}
+		case <-s.snapStopCh:
+			return
+		}
+	}
+}
+
+// recover rebuilds the in-memory state from the newest readable snapshot
+// plus the WAL segments written after it, and returns the segment to keep
+// appending to.
+func (s *Store) recover() (uint64, error) {
+	dir := s.cfg.Dir
+	if err := migrateLegacyWAL(dir); err != nil {
+		return 0, err
+	}
+	snaps, err := listSeqs(dir, snapshotPrefix, snapshotSuffix)
+	if err != nil {
+		return 0, err
+	}
+	var start uint64 = 1
+	for i := len(snaps) - 1; i >= 0; i-- {
+		snap, err := readSnapshot(filepath.Join(dir, snapshotName(snaps[i])))
+		if err != nil {
+			logger.Warn("store: skipping unreadable snapshot", "snapshot", snaps[i], "error", err)
+			continue
+		}
+		// The index is sorted once rather than kept sorted key by key.
+		maps.Copy(s.tasks, snap.Tasks)
+		s.keys = slices.Sorted(maps.Keys(s.tasks))
+		s.nextID = snap.NextID
+		start = snap.Seq
+		break
+	}
+
+	segments, err := listSeqs(dir, segmentPrefix, segmentSuffix)
+	if err != nil {
+		return 0, err
+	}
+	next := start
+	for i, seq := range segments {
+		if seq < start {
+			continue
+		}
+		if seq != next {
+			return 0, fmt.Errorf("wal segment %d is missing", next)
+		}
+		last := i == len(segments)-1
+		if err := replaySegment(filepath.Join(dir, segmentName(seq)), last, s.apply); err != nil {
+			return 0, err
+		}
+		next++
+	}
+	if next == start {
+		return start, nil
+	}
+	return next - 1, nil
+}
+
+// migrateLegacyWAL turns the single log file used before snapshots existed
+// into the first segment.
+func migrateLegacyWAL(dir string) error {
+	legacy := filepath.Join(dir, legacyWALFileName)
+	if _, err := os.Stat(legacy); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	segments, err := listSeqs(dir, segmentPrefix, segmentSuffix)
+	if err != nil {
+		return err
+	}
+	if len(segments) > 0 {
+		return fmt.Errorf("both %s and wal segments exist in %s", legacyWALFileName, dir)
+	}
+	return os.Rename(legacy, filepath.Join(dir, segmentName(1)))
+}
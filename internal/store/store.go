@@ -1,15 +1,44 @@
 package store
 
 import (
+	"fmt"
+	"os"
+	"slices"
 	"sync"
+	"time"
 
 	"task-manager/internal/models"
+	"task-manager/pkg/logger"
 )
 
+// legacyWALFileName is the single log file written before the WAL was split
+// into segments.
+const legacyWALFileName = "tasks.wal"
+
+type Config struct {
+	// Dir holds the write-ahead log and snapshots. An empty Dir keeps the
+	// store in memory only.
+	Dir          string
+	Sync         SyncPolicy
+	SyncInterval time.Duration
+	// SnapshotInterval of zero disables periodic snapshots.
+	SnapshotInterval time.Duration
+	SnapshotRetain   int
+}
+
 type Store struct {
-	tasks  map[int]models.Task
+	tasks map[int]models.Task
+	// keys are the keys of tasks in ascending order, so a scan can start at
+	// a cursor without sorting the map.
+	keys   []int
 	mu     sync.RWMutex
 	nextID int
+	wal    *wal
+	cfg    Config
+
+	snapMu     sync.Mutex
+	snapStopCh chan struct{}
+	snapDoneCh chan struct{}
 }
 
 func NewStore() *Store {
@@ -19,44 +48,151 @@ func NewStore() *Store {
 	}
 }
 
-func (s *Store) NextID() int {
+// OpenStore creates a store persisted in cfg.Dir, restoring the latest
+// snapshot and replaying the write-ahead log written after it.
+func OpenStore(cfg *Config) (*Store, error) {
+	s := NewStore()
+	if cfg == nil || cfg.Dir == "" {
+		return s, nil
+	}
+	s.cfg = *cfg
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	seq, err := s.recover()
+	if err != nil {
+		return nil, err
+	}
+	w, err := openWAL(cfg.Dir, seq, cfg.Sync, cfg.SyncInterval)
+	if err != nil {
+		return nil, err
+	}
+	s.wal = w
+	if cfg.SnapshotInterval > 0 {
+		s.snapStopCh = make(chan struct{})
+		s.snapDoneCh = make(chan struct{})
+		go s.snapshotLoop(cfg.SnapshotInterval)
+	}
+	return s, nil
+}
+
+func (s *Store) apply(rec record) error {
+	switch rec.Op {
+	case opSet:
+		if rec.Task == nil {
+			return fmt.Errorf("wal: set record for key %d has no task", rec.Key)
+		}
+		s.put(rec.Key, *rec.Task)
+	case opDelete:
+		s.remove(rec.Key)
+	case opNextID:
+		s.nextID = rec.NextID
+	case opBatch:
+		for _, r := range rec.Batch {
+			if err := s.apply(r); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("wal: unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// put stores task under key and keeps keys sorted; the caller holds s.mu.
+func (s *Store) put(key int, task models.Task) {
+	if _, ok := s.tasks[key]; !ok {
+		i, _ := slices.BinarySearch(s.keys, key)
+		s.keys = slices.Insert(s.keys, i, key)
+	}
+	s.tasks[key] = task
+}
+
+// remove deletes the task under key; the caller holds s.mu.
+func (s *Store) remove(key int) {
+	if _, ok := s.tasks[key]; !ok {
+		return
+	}
+	i, _ := slices.BinarySearch(s.keys, key)
+	s.keys = slices.Delete(s.keys, i, i+1)
+	delete(s.tasks, key)
+}
+
+// keysAfter returns the stored keys greater than after, in order. The slice
+// is shared; the caller holds s.mu while it reads it.
+func (s *Store) keysAfter(after int) []int {
+	i, found := slices.BinarySearch(s.keys, after)
+	if found {
+		i++
+	}
+	return s.keys[i:]
+}
+
+func (s *Store) log(rec record) error {
+	if s.wal == nil {
+		return nil
+	}
+	return s.wal.append(rec)
+}
+
+func (s *Store) NextID() (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	id := s.nextID
+	if err := s.log(record{Op: opNextID, NextID: id + 1}); err != nil {
+		return 0, err
+	}
 	s.nextID++
-	return id
+	return id, nil
 }
 
-func (s *Store) Get(key int) (models.Task, bool) {
+func (s *Store) Get(key int) (models.Task, bool, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	value, ok := s.tasks[key]
-	return value, ok
+	return value, ok, nil
 }
 
-func (s *Store) GetAllTasks() []models.Task {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	tasks := make([]models.Task, 0, len(s.tasks))
-	for _, task := range s.tasks {
-		tasks = append(tasks, task)
+func (s *Store) Set(key int, value models.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.log(record{Op: opSet, Key: key, Task: &value}); err != nil {
+		return err
 	}
-	return tasks
+	s.put(key, value)
+	return nil
 }
 
-func (s *Store) Set(key int, value models.Task) {
+func (s *Store) Delete(key int) (bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.tasks[key] = value
+	_, ok := s.tasks[key]
+	if !ok {
+		return false, nil
+	}
+	if err := s.log(record{Op: opDelete, Key: key}); err != nil {
+		return false, err
+	}
+	s.remove(key)
+	return true, nil
 }
 
-func (s *Store) Delete(key int) bool {
+func (s *Store) Close() error {
+	if s.snapStopCh != nil {
+		close(s.snapStopCh)
+		<-s.snapDoneCh
+		s.snapStopCh = nil
+		if err := s.Snapshot(); err != nil {
+			logger.Error("store: final snapshot failed", "error", err)
+		}
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	_, ok := s.tasks[key]
-	if !ok {
-		return false
+	if s.wal == nil {
+		return nil
 	}
-	delete(s.tasks, key)
-	return true
+	err := s.wal.close()
+	s.wal = nil
+	return err
 }
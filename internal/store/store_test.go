@@ -2,6 +2,9 @@ package store
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 
@@ -20,7 +23,7 @@ func TestGet(t *testing.T) {
 	task := models.Task{ID: 1, Title: "Task 1"}
 	store.Set(1, task)
 
-	got, ok := store.Get(1)
+	got, ok, _ := store.Get(1)
 	if !ok {
 		t.Errorf("expected task to be found")
 	}
@@ -31,7 +34,7 @@ func TestGet(t *testing.T) {
 
 func TestGetNotFound(t *testing.T) {
 	store := NewStore()
-	_, ok := store.Get(1)
+	_, ok, _ := store.Get(1)
 	if ok {
 		t.Errorf("expected task not to be found")
 	}
@@ -42,7 +45,7 @@ func TestSet(t *testing.T) {
 	task := models.Task{ID: 1, Title: "Task 1"}
 	store.Set(1, task)
 
-	got, ok := store.Get(1)
+	got, ok, _ := store.Get(1)
 	if !ok {
 		t.Errorf("expected task to be found")
 	}
@@ -58,7 +61,7 @@ func TestDelete(t *testing.T) {
 
 	store.Delete(1)
 
-	_, ok := store.Get(1)
+	_, ok, _ := store.Get(1)
 	if ok {
 		t.Errorf("expected task to be deleted")
 	}
@@ -80,7 +83,7 @@ func TestConcurrentAccess(t *testing.T) {
 	wg.Wait()
 
 	for i := 0; i < 10; i++ {
-		got, ok := store.Get(i)
+		got, ok, _ := store.Get(i)
 		if !ok {
 			t.Errorf("expected task to be found")
 		}
@@ -89,3 +92,201 @@ func TestConcurrentAccess(t *testing.T) {
 		}
 	}
 }
+
+func TestOpenStoreReplaysWAL(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{Dir: dir, Sync: SyncAlways}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		id, err := store.NextID()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := store.Set(id, models.Task{ID: id, Title: fmt.Sprintf("Task %d", id)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := store.Delete(2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reopened, err := OpenStore(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reopened.Close()
+
+	if _, ok, _ := reopened.Get(2); ok {
+		t.Errorf("expected deleted task to stay deleted")
+	}
+	got, ok, _ := reopened.Get(3)
+	if !ok || got.Title != "Task 3" {
+		t.Errorf("expected task 3 to be restored, got %v", got)
+	}
+	id, _ := reopened.NextID()
+	if id != 4 {
+		t.Errorf("expected next id 4, got %d", id)
+	}
+}
+
+func TestOpenStoreTruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{Dir: dir, Sync: SyncNever}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Set(1, models.Task{ID: 1, Title: "Task 1"})
+	store.Set(2, models.Task{ID: 2, Title: "Task 2"})
+	store.Close()
+
+	path := filepath.Join(dir, segmentName(1))
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reopened, err := OpenStore(cfg)
+	if err != nil {
+		t.Fatalf("expected torn tail to be tolerated, got %v", err)
+	}
+	if _, ok, _ := reopened.Get(1); !ok {
+		t.Errorf("expected task 1 to survive")
+	}
+	if _, ok, _ := reopened.Get(2); ok {
+		t.Errorf("expected torn task 2 to be discarded")
+	}
+	if err := reopened.Set(3, models.Task{ID: 3, Title: "Task 3"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reopened.Close()
+
+	again, err := OpenStore(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer again.Close()
+	if _, ok, _ := again.Get(3); !ok {
+		t.Errorf("expected record appended after truncation to be replayed")
+	}
+}
+
+func TestOpenStoreRejectsCorruptSealedSegment(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{Dir: dir, Sync: SyncNever}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Set(1, models.Task{ID: 1, Title: "Task 1"})
+	store.Close()
+
+	// A later segment makes the first one sealed, so damage to its last
+	// record is no torn tail.
+	first := filepath.Join(dir, segmentName(1))
+	data, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, segmentName(2)), data, 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data[len(data)-2] ^= 0xff
+	if err := os.WriteFile(first, data, 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if reopened, err := OpenStore(cfg); err == nil {
+		reopened.Close()
+		t.Fatalf("expected a corrupt sealed segment to fail the open")
+	}
+}
+
+func TestFailedAppendRefusesLaterWrites(t *testing.T) {
+	store, err := OpenStore(&Config{Dir: t.TempDir(), Sync: SyncNever})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	// With the file gone the failed write cannot be cut off either, so the
+	// log must not grow past it.
+	store.wal.file.Close()
+	if err := store.Set(1, models.Task{ID: 1}); err == nil {
+		t.Fatalf("expected the write to fail")
+	}
+	if err := store.Set(2, models.Task{ID: 2}); err == nil || !strings.Contains(err.Error(), "refusing writes") {
+		t.Errorf("expected writes after a failed one to be refused, got %v", err)
+	}
+}
+
+func TestSnapshotCompactsWAL(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{Dir: dir, Sync: SyncAlways, SnapshotRetain: 1}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Set(1, models.Task{ID: 1, Title: "Task 1"})
+	store.Set(2, models.Task{ID: 2, Title: "Task 2"})
+	if err := store.Snapshot(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Set(3, models.Task{ID: 3, Title: "Task 3"})
+	store.Delete(1)
+	if err := store.Snapshot(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Set(4, models.Task{ID: 4, Title: "Task 4"})
+	store.Close()
+
+	snaps, _ := listSeqs(dir, snapshotPrefix, snapshotSuffix)
+	if len(snaps) != 1 {
+		t.Errorf("expected 1 retained snapshot, got %d", len(snaps))
+	}
+	segments, _ := listSeqs(dir, segmentPrefix, segmentSuffix)
+	if len(segments) != 1 || segments[0] != snaps[0] {
+		t.Errorf("expected only the segment after the snapshot to remain, got %v", segments)
+	}
+
+	reopened, err := OpenStore(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reopened.Close()
+	for id, want := range map[int]bool{1: false, 2: true, 3: true, 4: true} {
+		if _, ok, _ := reopened.Get(id); ok != want {
+			t.Errorf("task %d: expected present=%v", id, want)
+		}
+	}
+}
+
+func TestSnapshotDoesNotBlockReaders(t *testing.T) {
+	store, err := OpenStore(&Config{Dir: t.TempDir(), Sync: SyncNever})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer store.Close()
+	store.Set(1, models.Task{ID: 1, Title: "Task 1"})
+
+	store.mu.RLock()
+	done := make(chan error)
+	go func() { done <- store.Snapshot() }()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.mu.RUnlock()
+}
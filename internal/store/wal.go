@@ -0,0 +1,294 @@
+package store
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"task-manager/internal/models"
+	"task-manager/pkg/logger"
+)
+
+type SyncPolicy int
+
+const (
+	SyncAlways SyncPolicy = iota
+	SyncInterval
+	SyncNever
+)
+
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch s {
+	case "", "always":
+		return SyncAlways, nil
+	case "interval":
+		return SyncInterval, nil
+	case "never":
+		return SyncNever, nil
+	default:
+		return 0, fmt.Errorf("unknown wal sync policy %q", s)
+	}
+}
+
+const (
+	opSet    = "set"
+	opDelete = "delete"
+	opNextID = "next_id"
+	opBatch  = "batch"
+
+	segmentPrefix = "wal-"
+	segmentSuffix = ".log"
+
+	walHeaderSize = 8
+	maxRecordSize = 64 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// record is a single WAL entry. On disk it is framed as
+// [payload length uint32][crc32c of payload uint32][json payload].
+type record struct {
+	Op     string       `json:"op"`
+	Key    int          `json:"key,omitempty"`
+	Task   *models.Task `json:"task,omitempty"`
+	NextID int          `json:"next_id,omitempty"`
+	Batch  []record     `json:"batch,omitempty"`
+}
+
+type wal struct {
+	mu     sync.Mutex
+	dir    string
+	seq    uint64
+	file   *os.File
+	size   int64
+	policy SyncPolicy
+	dirty  bool
+	// failed is set when a write could not be undone; the segment may end
+	// in a partial record, so nothing more is appended after it.
+	failed error
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+func segmentName(seq uint64) string {
+	return fmt.Sprintf("%s%020d%s", segmentPrefix, seq, segmentSuffix)
+}
+
+// openWAL opens segment seq in dir for appending, creating it if needed.
+func openWAL(dir string, seq uint64, policy SyncPolicy, interval time.Duration) (*wal, error) {
+	file, err := openSegment(dir, seq)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	w := &wal{dir: dir, seq: seq, file: file, size: info.Size(), policy: policy}
+	if policy == SyncInterval {
+		if interval <= 0 {
+			interval = time.Second
+		}
+		w.stopCh = make(chan struct{})
+		w.doneCh = make(chan struct{})
+		go w.syncLoop(interval)
+	}
+	return w, nil
+}
+
+func openSegment(dir string, seq uint64) (*os.File, error) {
+	file, err := os.OpenFile(filepath.Join(dir, segmentName(seq)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syncDir(dir); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// replaySegment feeds every intact record in the segment at path to apply.
+// In the last segment a torn or corrupt record marks the end of the log: it
+// and anything after it are truncated away. Earlier segments were sealed
+// whole, so damage there is an error rather than writes to drop.
+func replaySegment(path string, last bool, apply func(record) error) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		rec, n, err := readRecord(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && !last {
+			return fmt.Errorf("wal segment %s is corrupt at offset %d: %w", filepath.Base(path), offset, err)
+		}
+		if err != nil {
+			logger.Warn("wal: discarding torn tail", "segment", filepath.Base(path), "offset", offset, "error", err)
+			if err := file.Truncate(offset); err != nil {
+				return err
+			}
+			return file.Sync()
+		}
+		if err := apply(rec); err != nil {
+			return err
+		}
+		offset += n
+	}
+}
+
+func readRecord(r io.Reader) (record, int64, error) {
+	var header [walHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return record{}, 0, io.EOF
+		}
+		return record{}, 0, err
+	}
+	size := binary.LittleEndian.Uint32(header[0:4])
+	sum := binary.LittleEndian.Uint32(header[4:8])
+	if size > maxRecordSize {
+		return record{}, 0, fmt.Errorf("record size %d exceeds limit", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return record{}, 0, err
+	}
+	if crc32.Checksum(payload, crcTable) != sum {
+		return record{}, 0, errors.New("checksum mismatch")
+	}
+	var rec record
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return record{}, 0, err
+	}
+	return rec, int64(walHeaderSize + len(payload)), nil
+}
+
+func (w *wal) append(rec record) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, walHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	copy(buf[walHeaderSize:], payload)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failed != nil {
+		return fmt.Errorf("wal: refusing writes after a failed one: %w", w.failed)
+	}
+	if _, err := w.file.Write(buf); err != nil {
+		// Part of the record may have reached the file; cut it off, or
+		// later records would follow it and be lost as a torn tail.
+		if terr := w.file.Truncate(w.size); terr != nil {
+			w.failed = err
+			logger.Error("wal: cannot undo a failed write", "segment", w.seq, "error", terr)
+		}
+		return err
+	}
+	w.size += int64(len(buf))
+	switch w.policy {
+	case SyncAlways:
+		return w.file.Sync()
+	case SyncInterval:
+		w.dirty = true
+	}
+	return nil
+}
+
+// rotate seals the current segment and starts appending to the next one,
+// returning the sequence number of the new segment.
+func (w *wal) rotate() (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failed != nil {
+		return 0, fmt.Errorf("wal: refusing to seal a segment after a failed write: %w", w.failed)
+	}
+	if err := w.file.Sync(); err != nil {
+		return 0, err
+	}
+	file, err := openSegment(w.dir, w.seq+1)
+	if err != nil {
+		return 0, err
+	}
+	if err := w.file.Close(); err != nil {
+		logger.Error("wal: failed to close segment", "segment", w.seq, "error", err)
+	}
+	w.file = file
+	w.seq++
+	w.size = 0
+	w.dirty = false
+	return w.seq, nil
+}
+
+// empty reports whether nothing has been written to the current segment.
+func (w *wal) empty() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size == 0
+}
+
+func (w *wal) syncLoop(interval time.Duration) {
+	defer close(w.doneCh)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.dirty {
+				if err := w.file.Sync(); err != nil {
+					logger.Error("wal: fsync failed", "error", err)
+				} else {
+					w.dirty = false
+				}
+			}
+			w.mu.Unlock()
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+func (w *wal) close() error {
+	if w.stopCh != nil {
+		close(w.stopCh)
+		<-w.doneCh
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
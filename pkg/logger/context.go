@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l, which the *Context functions
+// log through.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the one set up by Init.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return Slog()
+}
+
+// With returns a copy of ctx whose logger adds the key-value pairs in args
+// to every record.
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+// DebugContext logs msg through the logger carried by ctx, with the fields
+// it was given; see With.
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	FromContext(ctx).Log(ctx, slog.LevelDebug, msg, args...)
+}
+
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	FromContext(ctx).Log(ctx, slog.LevelInfo, msg, args...)
+}
+
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	FromContext(ctx).Log(ctx, slog.LevelWarn, msg, args...)
+}
+
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	FromContext(ctx).Log(ctx, slog.LevelError, msg, args...)
+}
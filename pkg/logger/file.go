@@ -0,0 +1,257 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTime stamps rotated files, which are named <path>.<time>, plus .gz
+// once compressed. It sorts in time order.
+const backupTime = "20060102T150405.000"
+
+// FileConfig configures a log file and its rotation. Zero values disable
+// the corresponding limit.
+type FileConfig struct {
+	Path string
+	// MaxSize is the size in bytes past which the file is rotated.
+	MaxSize int64
+	// MaxAge is how long the file is written to before it is rotated,
+	// counted from when it was opened.
+	MaxAge time.Duration
+	// MaxBackups is how many rotated files are kept; older ones are removed.
+	MaxBackups int
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// File is a log file that rotates itself. Rotated files are compressed and
+// pruned in the background. Reopen lets an external tool such as logrotate
+// move the file away.
+type File struct {
+	cfg FileConfig
+	now func() time.Time
+
+	mu sync.Mutex
+	// f is nil when the file could not be reopened; the next write retries.
+	f      *os.File
+	size   int64
+	opened time.Time
+	closed bool
+
+	// cleanMu serializes compressing and pruning; wg tracks them for Close.
+	cleanMu sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// OpenFile opens cfg.Path for appending, creating it and its directory if
+// needed.
+func OpenFile(cfg FileConfig) (*File, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("log file path is empty")
+	}
+	if cfg.MaxSize < 0 || cfg.MaxAge < 0 || cfg.MaxBackups < 0 {
+		return nil, fmt.Errorf("log file %s: limits must not be negative", cfg.Path)
+	}
+	f := &File{cfg: cfg, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.cfg.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.f, f.size, f.opened = file, info.Size(), f.now()
+	return nil
+}
+
+// Write appends p, rotating first if p would take the file past MaxSize or
+// the file is older than MaxAge. A record is never split across files.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.f == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.size > 0 && f.due(len(p)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.f.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *File) due(n int) bool {
+	if f.cfg.MaxSize > 0 && f.size+int64(n) > f.cfg.MaxSize {
+		return true
+	}
+	return f.cfg.MaxAge > 0 && f.now().Sub(f.opened) >= f.cfg.MaxAge
+}
+
+// rotate moves the current file aside and starts a new one.
+func (f *File) rotate() error {
+	if err := f.f.Close(); err != nil {
+		return err
+	}
+	f.f = nil
+	backup := f.cfg.Path + "." + f.now().Format(backupTime)
+	if err := os.Rename(f.cfg.Path, backup); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	if f.cfg.Compress || f.cfg.MaxBackups > 0 {
+		f.wg.Add(1)
+		go f.cleanup()
+	}
+	return nil
+}
+
+// cleanup removes backups beyond MaxBackups and compresses the rest. Runs
+// may finish out of order, so each one looks at every backup rather than
+// the one its rotation made. It cannot log through the logger it writes
+// for, so failures go to stderr.
+func (f *File) cleanup() {
+	defer f.wg.Done()
+	f.cleanMu.Lock()
+	defer f.cleanMu.Unlock()
+
+	backups, err := f.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logger: listing backups of %s: %v\n", f.cfg.Path, err)
+		return
+	}
+	if f.cfg.MaxBackups > 0 && len(backups) > f.cfg.MaxBackups {
+		for _, name := range backups[:len(backups)-f.cfg.MaxBackups] {
+			if err := os.Remove(name); err != nil {
+				fmt.Fprintf(os.Stderr, "logger: removing %s: %v\n", name, err)
+			}
+		}
+		backups = backups[len(backups)-f.cfg.MaxBackups:]
+	}
+	if f.cfg.Compress {
+		for _, name := range backups {
+			if strings.HasSuffix(name, ".gz") {
+				continue
+			}
+			if err := compressFile(name); err != nil {
+				fmt.Fprintf(os.Stderr, "logger: compressing %s: %v\n", name, err)
+			}
+		}
+	}
+}
+
+// compressFile replaces path with path.gz. The archive is written under a
+// temporary name, so a partial one is never taken for a backup.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// backups lists the rotated files of the log, oldest first.
+func (f *File) backups() ([]string, error) {
+	dir, base := filepath.Split(f.cfg.Path)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), base+".")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		if _, err := time.Parse(backupTime, strings.TrimSuffix(stamp, ".gz")); err != nil {
+			continue
+		}
+		names = append(names, filepath.Join(dir, entry.Name()))
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Reopen closes the file and opens Path again, so writing continues in a
+// new file after the old one was moved away.
+func (f *File) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.f != nil {
+		err := f.f.Close()
+		f.f = nil
+		if err != nil {
+			return err
+		}
+	}
+	return f.open()
+}
+
+// Close closes the file once background compression has finished.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.wg.Wait()
+	f.closed = true
+	if f.f == nil {
+		return nil
+	}
+	err := f.f.Close()
+	f.f = nil
+	return err
+}
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a File clock that advances by step on every reading,
+// so each rotation gets a distinct backup name.
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func readBackup(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFileRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	f, err := OpenFile(FileConfig{Path: path, MaxSize: 10, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.now = fakeClock(time.Second)
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n", "six\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := f.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups to be kept, got %v", backups)
+	}
+	var got []string
+	for _, name := range backups {
+		if !strings.HasSuffix(name, ".gz") {
+			t.Errorf("expected %s to be compressed", name)
+		}
+		got = append(got, readBackup(t, name))
+	}
+	got = append(got, readBackup(t, path))
+	if want := []string{"three\n", "four\nfive\n", "six\n"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("files hold %q, want %q", got, want)
+	}
+}
+
+func TestFileRotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := OpenFile(FileConfig{Path: path, MaxAge: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	now := f.opened
+	f.now = func() time.Time { return now }
+
+	now = now.Add(40 * time.Minute)
+	f.Write([]byte("early\n"))
+	now = now.Add(40 * time.Minute)
+	f.Write([]byte("late\n"))
+
+	backups, _ := f.backups()
+	if len(backups) != 1 || readBackup(t, backups[0]) != "early\n" {
+		t.Fatalf("expected one backup holding the early line, got %v", backups)
+	}
+	if got := readBackup(t, path); got != "late\n" {
+		t.Errorf("current file holds %q", got)
+	}
+}
+
+func TestFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f, err := OpenFile(FileConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("before\n"))
+	// What logrotate does before sending SIGHUP.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("moved\n"))
+	if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("after\n"))
+
+	if got := readBackup(t, path+".1"); got != "before\nmoved\n" {
+		t.Errorf("moved file holds %q", got)
+	}
+	if got := readBackup(t, path); got != "after\n" {
+		t.Errorf("reopened file holds %q", got)
+	}
+}
+
+func TestLoggerSinks(t *testing.T) {
+	var text bytes.Buffer
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := OpenFile(FileConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLogger(&LoggerConfig{Enabled: true, Level: slog.LevelInfo, Sinks: []Sink{
+		{Output: &text},
+		{Output: f, Format: "json"},
+	}})
+	l.slog.With("component", "test").Info("task created", "task_id", 5)
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(text.String(), `msg="task created" component=test task_id=5`) {
+		t.Errorf("unexpected text sink output %q", text.String())
+	}
+	if got := readBackup(t, path); !strings.Contains(got, `"msg":"task created","component":"test","task_id":5`) {
+		t.Errorf("unexpected file sink output %q", got)
+	}
+	if _, err := f.Write([]byte("late\n")); err == nil {
+		t.Errorf("expected Close to close the file sink")
+	}
+}
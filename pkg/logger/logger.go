@@ -1,12 +1,27 @@
+// Package logger is the application's structured logger. It is built on
+// log/slog: records are queued to a goroutine that writes them through a
+// text or JSON slog handler, so logging does not wait on the output unless
+// the queue is full.
+//
+//	logger.Info("task created", "task_id", id)
 package logger
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// LevelFatal is above slog.LevelError. Fatal logs at it and exits.
+const LevelFatal = slog.Level(12)
+
 var (
 	loggerOnce sync.Once
 	logger     *Logger
@@ -22,60 +37,407 @@ func Init(cfg *LoggerConfig) {
 	})
 }
 
+// Overflow is what happens to a record when the queue is full.
+type Overflow string
+
+const (
+	// Block makes the caller wait for room in the queue.
+	Block Overflow = "block"
+	// DropNewest discards the record being logged.
+	DropNewest Overflow = "drop_newest"
+	// DropOldest discards the oldest queued record to make room.
+	DropOldest Overflow = "drop_oldest"
+)
+
+// ParseOverflow reads an overflow policy; empty means Block.
+func ParseOverflow(s string) (Overflow, error) {
+	switch Overflow(s) {
+	case "", Block:
+		return Block, nil
+	case DropNewest, DropOldest:
+		return Overflow(s), nil
+	default:
+		return "", fmt.Errorf("log overflow must be %q, %q or %q, got %q", Block, DropNewest, DropOldest, s)
+	}
+}
+
+const defaultBufferSize = 100
+
 type LoggerConfig struct {
 	Enabled bool
+	// Level is the least severe level written.
+	Level slog.Level
+	// Format is "text" (the default) or "json".
+	Format string
+	// Output defaults to os.Stderr. It is ignored when Sinks are given.
+	Output io.Writer
+	// Sinks receive every record, each in its own format.
+	Sinks []Sink
+	// BufferSize is how many records may wait to be written; zero means 100.
+	BufferSize int
+	// Overflow applies when BufferSize records are waiting.
+	Overflow Overflow
+}
+
+// Sink is one destination of the log. A *File sink is reopened by Reopen
+// and closed by Close.
+type Sink struct {
+	Output io.Writer
+	// Format overrides LoggerConfig.Format for this sink.
+	Format string
+}
+
+// ParseLevel reads a level name: debug, info, warn, error or fatal. Empty
+// means info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "fatal":
+		return LevelFatal, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+// ParseFormat checks a format name; empty means text.
+func ParseFormat(s string) (string, error) {
+	switch s {
+	case "", "text":
+		return "text", nil
+	case "json":
+		return "json", nil
+	default:
+		return "", fmt.Errorf("log format must be \"text\" or \"json\", got %q", s)
+	}
 }
 
 type Logger struct {
 	cfg   *LoggerConfig
 	logCh chan *logMessage
+	done  chan struct{}
+	slog  *slog.Logger
+	files []*File
+
+	// mu guards closed; senders hold it shared so Close cannot close logCh
+	// under them.
+	mu      sync.RWMutex
+	closed  bool
+	dropped atomic.Int64
 }
 
 type logMessage struct {
-	timestamp string
-	level     string
-	message   string
+	handler slog.Handler
+	record  slog.Record
 }
 
 func NewLogger(cfg *LoggerConfig) *Logger {
-	logCh := make(chan *logMessage, 100)
+	sinks := cfg.Sinks
+	if len(sinks) == 0 {
+		out := cfg.Output
+		if out == nil {
+			out = os.Stderr
+		}
+		sinks = []Sink{{Output: out}}
+	}
+	opts := &slog.HandlerOptions{Level: cfg.Level, ReplaceAttr: replaceLevel}
+	var handlers multiHandler
+	var files []*File
+	for _, sink := range sinks {
+		format := sink.Format
+		if format == "" {
+			format = cfg.Format
+		}
+		if format == "json" {
+			handlers = append(handlers, slog.NewJSONHandler(sink.Output, opts))
+		} else {
+			handlers = append(handlers, slog.NewTextHandler(sink.Output, opts))
+		}
+		if f, ok := sink.Output.(*File); ok {
+			files = append(files, f)
+		}
+	}
+	var handler slog.Handler = handlers
+	if len(handlers) == 1 {
+		handler = handlers[0]
+	}
+
+	size := cfg.BufferSize
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+	l := &Logger{
+		cfg:   cfg,
+		logCh: make(chan *logMessage, size),
+		done:  make(chan struct{}),
+		files: files,
+	}
 	go func() {
-		for msg := range logCh {
-			logLine := fmt.Sprintf("[%s] [%s] %s", msg.timestamp, msg.level, msg.message)
-			log.Println(logLine)
+		defer close(l.done)
+		for msg := range l.logCh {
+			msg.handler.Handle(context.Background(), msg.record)
 		}
 	}()
+	l.slog = slog.New(&asyncHandler{l: l, next: handler})
+	return l
+}
 
-	return &Logger{
-		cfg:   cfg,
-		logCh: logCh,
+// replaceLevel names LevelFatal, which slog would print as "ERROR+4".
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == LevelFatal {
+			a.Value = slog.StringValue("FATAL")
+		}
 	}
+	return a
 }
 
 func (l *Logger) log(logMsg *logMessage) {
-	if l.cfg.Enabled && logMsg != nil {
+	if !l.cfg.Enabled || logMsg == nil {
+		return
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		l.dropped.Add(1)
+		return
+	}
+
+	switch l.cfg.Overflow {
+	case DropNewest:
+		select {
+		case l.logCh <- logMsg:
+		default:
+			l.dropped.Add(1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case l.logCh <- logMsg:
+				return
+			default:
+			}
+			select {
+			case <-l.logCh:
+				l.dropped.Add(1)
+			default:
+			}
+		}
+	default:
 		l.logCh <- logMsg
 	}
 }
 
-func LogError(msg string) {
-	if logger == nil {
+// Close stops accepting records and waits until the queued ones are
+// written, or until ctx is done, then closes the file sinks. Records logged
+// afterwards are dropped.
+func (l *Logger) Close(ctx context.Context) error {
+	if l.logCh == nil {
+		return nil
+	}
+	// A sender blocked on a full queue holds mu until the writer makes
+	// room, so the lock is taken apart from the wait on ctx.
+	go func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if !l.closed {
+			l.closed = true
+			close(l.logCh)
+		}
+	}()
+
+	select {
+	case <-l.done:
+		var errs []error
+		for _, f := range l.files {
+			errs = append(errs, f.Close())
+		}
+		return errors.Join(errs...)
+	case <-ctx.Done():
+		return fmt.Errorf("logger: %d records not written: %w", len(l.logCh), ctx.Err())
+	}
+}
+
+// Reopen reopens the file sinks, e.g. after logrotate has moved them.
+func (l *Logger) Reopen() error {
+	var errs []error
+	for _, f := range l.files {
+		errs = append(errs, f.Reopen())
+	}
+	return errors.Join(errs...)
+}
+
+// Dropped is the number of records lost to the overflow policy or logged
+// after Close.
+func (l *Logger) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// Stats reports the queue of the logger: its capacity, the records waiting
+// in it and the records dropped.
+func (l *Logger) Stats() map[string]any {
+	stats := map[string]any{"dropped": l.Dropped(), "queued": len(l.logCh)}
+	if l.logCh != nil {
+		stats["capacity"] = cap(l.logCh)
+	}
+	return stats
+}
+
+// asyncHandler queues records for the logger's goroutine, which passes them
+// to next.
+type asyncHandler struct {
+	l    *Logger
+	next slog.Handler
+}
+
+func (h *asyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *asyncHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.l.log(&logMessage{handler: h.next, record: r.Clone()})
+	return nil
+}
+
+func (h *asyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &asyncHandler{l: h.l, next: h.next.WithAttrs(attrs)}
+}
+
+func (h *asyncHandler) WithGroup(name string) slog.Handler {
+	return &asyncHandler{l: h.l, next: h.next.WithGroup(name)}
+}
+
+// multiHandler passes each record to every sink's handler.
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range m {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := make(multiHandler, len(m))
+	for i, h := range m {
+		next[i] = h.WithAttrs(attrs)
+	}
+	return next
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	next := make(multiHandler, len(m))
+	for i, h := range m {
+		next[i] = h.WithGroup(name)
+	}
+	return next
+}
+
+// Slog returns the logger as a *slog.Logger, for code that uses the slog
+// API directly. It discards everything until Init has enabled logging.
+func Slog() *slog.Logger {
+	if logger == nil || logger.slog == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return logger.slog
+}
+
+func log(level slog.Level, msg string, args ...any) {
+	if logger == nil || logger.slog == nil {
 		return
 	}
-	logger.log(&logMessage{
-		timestamp: time.Now().Format(time.RFC3339),
-		level:     "ERROR",
-		message:   msg,
-	})
+	logger.slog.Log(context.Background(), level, msg, args...)
 }
 
+// Debug logs msg with key-value pairs in args, like slog.Debug.
+func Debug(msg string, args ...any) {
+	log(slog.LevelDebug, msg, args...)
+}
+
+func Info(msg string, args ...any) {
+	log(slog.LevelInfo, msg, args...)
+}
+
+func Warn(msg string, args ...any) {
+	log(slog.LevelWarn, msg, args...)
+}
+
+func Error(msg string, args ...any) {
+	log(slog.LevelError, msg, args...)
+}
+
+// LogInfo logs msg at the info level.
+//
+// Deprecated: Use Info, which also takes key-value pairs.
 func LogInfo(msg string) {
+	Info(msg)
+}
+
+// LogError logs msg at the error level.
+//
+// Deprecated: Use Error, which also takes key-value pairs.
+func LogError(msg string) {
+	Error(msg)
+}
+
+// Fatal logs msg, waits a few seconds for the queued records to be written
+// and exits with status 1.
+func Fatal(msg string, args ...any) {
+	log(LevelFatal, msg, args...)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	Close(ctx)
+	cancel()
+	os.Exit(1)
+}
+
+// Close flushes the logger set up by Init; see Logger.Close. It is meant
+// for the end of a graceful shutdown.
+func Close(ctx context.Context) error {
 	if logger == nil {
-		return
+		return nil
 	}
-	logger.log(&logMessage{
-		timestamp: time.Now().Format(time.RFC3339),
-		level:     "INFO",
-		message:   msg,
-	})
+	return logger.Close(ctx)
+}
+
+// Reopen reopens the file sinks of the logger set up by Init; main calls it
+// on SIGHUP.
+func Reopen() error {
+	if logger == nil {
+		return nil
+	}
+	return logger.Reopen()
+}
+
+// Stats reports the queue of the logger set up by Init; see Logger.Stats.
+func Stats() map[string]any {
+	if logger == nil {
+		return map[string]any{"dropped": int64(0), "queued": 0}
+	}
+	return logger.Stats()
+}
+
+// Dropped reports the records the logger set up by Init has lost.
+func Dropped() int64 {
+	if logger == nil {
+		return 0
+	}
+	return logger.Dropped()
 }
@@ -0,0 +1,197 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&LoggerConfig{Enabled: true, Level: slog.LevelInfo, Format: "json", Output: &buf})
+	l.slog.Debug("hidden")
+	l.slog.Info("task created", "task_id", 5)
+	l.slog.With("component", "test").Log(context.Background(), LevelFatal, "stopping")
+	l.Close(context.Background())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected the debug line to be filtered out, got %q", lines)
+	}
+	var first, second map[string]any
+	json.Unmarshal([]byte(lines[0]), &first)
+	json.Unmarshal([]byte(lines[1]), &second)
+	if first["level"] != "INFO" || first["msg"] != "task created" || first["task_id"] != float64(5) {
+		t.Errorf("unexpected first record %v", first)
+	}
+	if second["level"] != "FATAL" || second["component"] != "test" {
+		t.Errorf("unexpected second record %v", second)
+	}
+}
+
+func TestLoggerText(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&LoggerConfig{Enabled: true, Level: slog.LevelDebug, Output: &buf})
+	l.slog.Debug("task not found", "task_id", 7)
+	l.Close(context.Background())
+
+	if line := buf.String(); !strings.Contains(line, "level=DEBUG") || !strings.Contains(line, `msg="task not found" task_id=7`) {
+		t.Errorf("unexpected text record %q", line)
+	}
+}
+
+func TestDeprecatedHelpers(t *testing.T) {
+	var buf bytes.Buffer
+	saved := logger
+	logger = NewLogger(&LoggerConfig{Enabled: true, Level: slog.LevelInfo, Output: &buf})
+	defer func() { logger = saved }()
+
+	LogInfo("started")
+	LogError("failed")
+	logger.Close(context.Background())
+
+	if out := buf.String(); !strings.Contains(out, "level=INFO msg=started") || !strings.Contains(out, "level=ERROR msg=failed") {
+		t.Errorf("unexpected records %q", out)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]slog.Level{"": slog.LevelInfo, "debug": slog.LevelDebug, "WARN": slog.LevelWarn, "fatal": LevelFatal} {
+		if got, err := ParseLevel(name); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("expected an unknown level to be rejected")
+	}
+}
+
+// gatedWriter holds every write until open is closed, and signals the first.
+type gatedWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	open    chan struct{}
+	started chan struct{}
+	once    sync.Once
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{open: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.open
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestLoggerOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		overflow Overflow
+		want     []string
+	}{
+		{DropNewest, []string{"first", "second"}},
+		{DropOldest, []string{"first", "fourth"}},
+	} {
+		t.Run(string(tc.overflow), func(t *testing.T) {
+			w := newGatedWriter()
+			l := NewLogger(&LoggerConfig{Enabled: true, Output: w, Format: "json", BufferSize: 1, Overflow: tc.overflow})
+			// "first" is taken by the writer, which then waits; the queue
+			// holds one record, so two of the other three are dropped.
+			l.slog.Info("first")
+			<-w.started
+			l.slog.Info("second")
+			l.slog.Info("third")
+			l.slog.Info("fourth")
+			close(w.open)
+			if err := l.Close(context.Background()); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(w.String()), "\n") {
+				var record map[string]any
+				json.Unmarshal([]byte(line), &record)
+				got = append(got, record["msg"].(string))
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("written %v, want %v", got, tc.want)
+			}
+			if l.Dropped() != 2 {
+				t.Errorf("dropped %d, want 2", l.Dropped())
+			}
+		})
+	}
+}
+
+func TestLoggerCloseDrains(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&LoggerConfig{Enabled: true, Output: &buf, BufferSize: 1000})
+	for i := range 500 {
+		l.slog.Info("queued", "n", i)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 500 {
+		t.Errorf("wrote %d records before Close returned, want 500", n)
+	}
+
+	l.slog.Info("late")
+	if l.Dropped() != 1 || strings.Contains(buf.String(), "late") {
+		t.Errorf("expected a record logged after Close to be dropped, dropped %d", l.Dropped())
+	}
+}
+
+func TestLoggerCloseTimeout(t *testing.T) {
+	w := newGatedWriter()
+	defer close(w.open)
+	l := NewLogger(&LoggerConfig{Enabled: true, Output: w, BufferSize: 1})
+	l.slog.Info("stuck")
+	<-w.started
+	l.slog.Info("queued")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Close(ctx); err == nil {
+		t.Errorf("expected Close to give up when the output is stuck")
+	}
+}
+
+func TestParseOverflow(t *testing.T) {
+	if got, err := ParseOverflow(""); err != nil || got != Block {
+		t.Errorf("ParseOverflow(\"\") = %q, %v; want block", got, err)
+	}
+	if _, err := ParseOverflow("drop_all"); err == nil {
+		t.Errorf("expected an unknown policy to be rejected")
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := NewContext(context.Background(), slog.New(slog.NewTextHandler(&buf, nil)))
+	ctx = With(ctx, "request_id", "abc")
+	InfoContext(ctx, "task updated", "task_id", 3)
+	DebugContext(ctx, "hidden")
+
+	if line := buf.String(); !strings.Contains(line, `msg="task updated" request_id=abc task_id=3`) || strings.Contains(line, "hidden") {
+		t.Errorf("unexpected record %q", line)
+	}
+	// Without a logger in the context, records go to the one set up by Init.
+	if FromContext(context.Background()) == nil {
+		t.Errorf("expected a fallback logger")
+	}
+}